@@ -7,19 +7,33 @@ import "context"
 type WatcherAdapter[S any] struct {
 	componentType string
 	watcher       TypedWatcher[S]
+	options       *StreamOptions
 }
 
 // NewWatcherAdapter creates an adapter for the given typed watcher.
-func NewWatcherAdapter[S any](componentType string, w TypedWatcher[S]) *WatcherAdapter[S] {
+// By default each snapshot stream buffers 10 snapshots and blocks when full;
+// use StreamOptions to change the buffer size or backpressure policy.
+func NewWatcherAdapter[S any](componentType string, w TypedWatcher[S], opts ...StreamOption) *WatcherAdapter[S] {
 	return &WatcherAdapter[S]{
 		componentType: componentType,
 		watcher:       w,
+		options:       newStreamOptions(10, opts),
 	}
 }
 
+// ComponentType returns the component type the adapter stamps on every snapshot.
+func (a *WatcherAdapter[S]) ComponentType() string {
+	return a.componentType
+}
+
 // Snapshots converts the typed state change stream into snapshot envelopes.
 func (a *WatcherAdapter[S]) Snapshots(ctx context.Context) <-chan StateSnapshot {
-	ch := make(chan StateSnapshot, 10)
+	return bufferStream(ctx, a.snapshots(ctx), a.options, snapshotKey)
+}
+
+// snapshots converts the change stream into an unbuffered snapshot channel.
+func (a *WatcherAdapter[S]) snapshots(ctx context.Context) <-chan StateSnapshot {
+	ch := make(chan StateSnapshot)
 
 	go func() {
 		defer close(ch)
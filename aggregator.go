@@ -2,34 +2,54 @@ package introspection
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
 )
 
+var (
+	// ErrNilWatcher is reported when a nil watcher is registered for aggregation.
+	ErrNilWatcher = errors.New("introspection: nil watcher")
+
+	// ErrNoComponentType is reported when a watcher has no component type and
+	// none was supplied explicitly.
+	ErrNoComponentType = errors.New("introspection: missing component type")
+
+	// ErrNotWatcher is reported when a value has no Watch method returning a
+	// channel of StateChange values.
+	ErrNotWatcher = errors.New("introspection: not a watcher")
+)
+
 // AggregateWatchers combines multiple typed watchers into a unified snapshot stream.
+// Watchers are discovered via reflection; values that are nil, do not implement
+// Component or expose a Watch method returning a StateChange channel are skipped silently.
+//
+// The unified stream buffers 64 snapshots and blocks when full; use
+// AggregateWatchersWith to change the buffer size or backpressure policy.
+//
+// Prefer Aggregator with AddWatcher for compile-time checked registration.
 func AggregateWatchers(ctx context.Context, watchers ...interface{}) <-chan StateSnapshot {
-	out := make(chan StateSnapshot, 64)
-	var wg sync.WaitGroup
+	return mergeWatchers(ctx, 64, watchers)
+}
 
-	for _, w := range watchers {
-		compType := inferComponentType(w)
-		if compType == "" {
-			continue
-		}
+// AggregateWatchersWith is like AggregateWatchers, buffering the unified stream according to opts.
+func AggregateWatchersWith(ctx context.Context, watchers []interface{}, opts ...StreamOption) <-chan StateSnapshot {
+	return BufferSnapshots(ctx, mergeWatchers(ctx, 0, watchers), opts...)
+}
 
-		v := reflect.ValueOf(w)
-		watchMethod := v.MethodByName("Watch")
-		if !watchMethod.IsValid() {
-			continue
-		}
+// mergeWatchers fans in watchers into a channel with the given capacity.
+func mergeWatchers(ctx context.Context, size int, watchers []interface{}) <-chan StateSnapshot {
+	out := make(chan StateSnapshot, size)
+	var wg sync.WaitGroup
 
-		results := watchMethod.Call([]reflect.Value{reflect.ValueOf(ctx)})
-		if len(results) == 0 {
+	for _, w := range watchers {
+		compType, ch, err := watchByReflection(ctx, w)
+		if err != nil {
 			continue
 		}
 
-		ch := results[0]
 		wg.Add(1)
 		go func(componentType string, changeChan reflect.Value) {
 			defer wg.Done()
@@ -40,10 +60,11 @@ func AggregateWatchers(ctx context.Context, watchers ...interface{}) <-chan Stat
 					return
 				}
 
+				timestamp, _ := val.FieldByName("Timestamp").Interface().(time.Time)
 				snapshot := StateSnapshot{
 					ComponentID:   val.FieldByName("ComponentID").String(),
 					ComponentType: componentType,
-					Timestamp:     val.FieldByName("Timestamp").Interface().(time.Time),
+					Timestamp:     timestamp,
 					Payload:       val.FieldByName("NewState").Interface(),
 				}
 
@@ -64,9 +85,63 @@ func AggregateWatchers(ctx context.Context, watchers ...interface{}) <-chan Stat
 	return out
 }
 
+// watchByReflection calls the Watch method of w and returns its component type and
+// change channel. It checks the shape of the channel's elements up front, so that
+// reading ComponentID, Timestamp and NewState from them cannot panic later.
+func watchByReflection(ctx context.Context, w interface{}) (string, reflect.Value, error) {
+	if isNilWatcher(w) {
+		return "", reflect.Value{}, ErrNilWatcher
+	}
+	compType := inferComponentType(w)
+	if compType == "" {
+		return "", reflect.Value{}, ErrNoComponentType
+	}
+
+	watch := reflect.ValueOf(w).MethodByName("Watch")
+	if !watch.IsValid() {
+		return "", reflect.Value{}, fmt.Errorf("%w: %T has no Watch method", ErrNotWatcher, w)
+	}
+	mt := watch.Type()
+	if mt.NumIn() != 1 || !reflect.TypeOf(ctx).AssignableTo(mt.In(0)) || mt.NumOut() == 0 {
+		return "", reflect.Value{}, fmt.Errorf("%w: %T.Watch is %s", ErrNotWatcher, w, mt)
+	}
+	if ct := mt.Out(0); ct.Kind() != reflect.Chan || ct.ChanDir()&reflect.RecvDir == 0 || !isStateChange(ct.Elem()) {
+		return "", reflect.Value{}, fmt.Errorf("%w: %T.Watch returns %s", ErrNotWatcher, w, ct)
+	}
+
+	ch := watch.Call([]reflect.Value{reflect.ValueOf(ctx)})[0]
+	if ch.IsNil() {
+		return "", reflect.Value{}, fmt.Errorf("%w: %T.Watch returned a nil channel", ErrNotWatcher, w)
+	}
+	return compType, ch, nil
+}
+
+// isStateChange reports whether t is a struct with the exported StateChange fields
+// AggregateWatchers reads.
+func isStateChange(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	id, ok := t.FieldByName("ComponentID")
+	if !ok || !id.IsExported() || id.Type.Kind() != reflect.String {
+		return false
+	}
+	ts, ok := t.FieldByName("Timestamp")
+	if !ok || !ts.IsExported() || ts.Type != reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	state, ok := t.FieldByName("NewState")
+	return ok && state.IsExported()
+}
+
+// isNilWatcher reports whether w is nil or holds a nil pointer, map, channel or function.
+func isNilWatcher(w interface{}) bool {
+	return w == nil || isNilableAndNil(reflect.ValueOf(w))
+}
+
 // inferComponentType determines the component type from a watcher using reflection.
 func inferComponentType(watcher interface{}) string {
-	if watcher == nil {
+	if isNilWatcher(watcher) {
 		return ""
 	}
 
@@ -79,8 +154,20 @@ func inferComponentType(watcher interface{}) string {
 }
 
 // AggregateEvents combines multiple event sources into a unified event stream.
+// The stream buffers 64 events and blocks when full; use AggregateEventsWith to
+// change the buffer size or backpressure policy.
 func AggregateEvents(ctx context.Context, sources ...EventSource) <-chan ComponentEvent {
-	out := make(chan ComponentEvent, 64)
+	return mergeEvents(ctx, 64, sources)
+}
+
+// AggregateEventsWith is like AggregateEvents, buffering the unified stream according to opts.
+func AggregateEventsWith(ctx context.Context, sources []EventSource, opts ...StreamOption) <-chan ComponentEvent {
+	return BufferEvents(ctx, mergeEvents(ctx, 0, sources), opts...)
+}
+
+// mergeEvents fans in sources into a channel with the given capacity.
+func mergeEvents(ctx context.Context, size int, sources []EventSource) <-chan ComponentEvent {
+	out := make(chan ComponentEvent, size)
 	var wg sync.WaitGroup
 
 	for _, src := range sources {
@@ -104,3 +191,121 @@ func AggregateEvents(ctx context.Context, sources ...EventSource) <-chan Compone
 
 	return out
 }
+
+// AggregateSnapshots combines multiple snapshot sources into a unified snapshot stream.
+func AggregateSnapshots(ctx context.Context, sources ...SnapshotSource) <-chan StateSnapshot {
+	return mergeSnapshots(ctx, 64, sources)
+}
+
+// mergeSnapshots fans in sources into a channel with the given capacity.
+func mergeSnapshots(ctx context.Context, size int, sources []SnapshotSource) <-chan StateSnapshot {
+	out := make(chan StateSnapshot, size)
+	var wg sync.WaitGroup
+
+	for _, src := range sources {
+		wg.Add(1)
+		go func(source SnapshotSource) {
+			defer wg.Done()
+			for snapshot := range source.Snapshots(ctx) {
+				select {
+				case out <- snapshot:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(src)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Aggregator is a type-safe builder for a unified snapshot stream.
+// Unlike AggregateWatchers, watchers are registered through AddWatcher, which
+// checks their state type at compile time and reports rejected watchers as errors.
+type Aggregator struct {
+	mu      sync.Mutex
+	sources []SnapshotSource
+	errs    []error
+	options *StreamOptions
+}
+
+// NewAggregator creates an empty Aggregator.
+// By default the unified stream buffers 64 snapshots and blocks when full;
+// use StreamOptions to change the buffer size or backpressure policy.
+func NewAggregator(opts ...StreamOption) *Aggregator {
+	return &Aggregator{options: newStreamOptions(64, opts)}
+}
+
+// AddWatcher registers a typed watcher with the aggregator.
+// If componentType is empty, it is taken from the watcher's Component implementation.
+// A rejected watcher is not added; the returned error is also recorded and available via Err.
+func AddWatcher[S any](a *Aggregator, componentType string, w TypedWatcher[S]) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	index := len(a.sources) + len(a.errs)
+
+	var err error
+	if isNilWatcher(w) {
+		err = ErrNilWatcher
+	} else if componentType == "" {
+		componentType = inferComponentType(w)
+		if componentType == "" {
+			err = ErrNoComponentType
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("watcher %d (%T): %w", index, w, err)
+		a.errs = append(a.errs, err)
+		return err
+	}
+
+	a.sources = append(a.sources, NewWatcherAdapter(componentType, w))
+	return nil
+}
+
+// AddSource registers an arbitrary snapshot source with the aggregator.
+func (a *Aggregator) AddSource(src SnapshotSource) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if isNilWatcher(src) {
+		err := fmt.Errorf("source %d: %w", len(a.sources)+len(a.errs), ErrNilWatcher)
+		a.errs = append(a.errs, err)
+		return err
+	}
+
+	a.sources = append(a.sources, src)
+	return nil
+}
+
+// Err returns all registration errors joined together, or nil if every watcher was accepted.
+func (a *Aggregator) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return errors.Join(a.errs...)
+}
+
+// Len returns the number of accepted sources.
+func (a *Aggregator) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.sources)
+}
+
+// Snapshots starts watching every accepted source and returns the unified stream.
+// The channel is closed when the provided context is cancelled or every source has closed.
+func (a *Aggregator) Snapshots(ctx context.Context) <-chan StateSnapshot {
+	a.mu.Lock()
+	sources := make([]SnapshotSource, len(a.sources))
+	copy(sources, a.sources)
+	a.mu.Unlock()
+
+	return bufferStream(ctx, mergeSnapshots(ctx, 0, sources), a.options, snapshotKey)
+}
@@ -0,0 +1,185 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// untypedWatcher implements TypedWatcher but not Component.
+type untypedWatcher struct {
+	inner *MockTypedWatcher[MockState]
+}
+
+func (u untypedWatcher) State() MockState {
+	return u.inner.State()
+}
+
+func (u untypedWatcher) Watch(ctx context.Context) <-chan StateChange[MockState] {
+	return u.inner.Watch(ctx)
+}
+
+func TestAggregator_AddWatcher_InfersComponentType(t *testing.T) {
+	watcher := NewMockTypedWatcher(MockState{Value: "initial"})
+	agg := NewAggregator()
+
+	if err := AddWatcher[MockState](agg, "", watcher); err != nil {
+		t.Fatalf("AddWatcher() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	snapshots := agg.Snapshots(ctx)
+	watcher.SendChange(StateChange[MockState]{
+		ComponentID: "worker-1",
+		NewState:    MockState{Value: "updated"},
+		Timestamp:   time.Now(),
+	})
+
+	select {
+	case snap := <-snapshots:
+		if snap.ComponentType != "worker" {
+			t.Errorf("ComponentType = %q, want %q", snap.ComponentType, "worker")
+		}
+		if snap.ComponentID != "worker-1" {
+			t.Errorf("ComponentID = %q, want %q", snap.ComponentID, "worker-1")
+		}
+		if state, ok := snap.Payload.(MockState); !ok || state.Value != "updated" {
+			t.Errorf("Payload = %v, want updated MockState", snap.Payload)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for snapshot")
+	}
+}
+
+func TestAggregator_AddWatcher_ExplicitComponentType(t *testing.T) {
+	watcher := untypedWatcher{NewMockTypedWatcher(MockState{})}
+	agg := NewAggregator()
+
+	if err := AddWatcher[MockState](agg, "", watcher); !errors.Is(err, ErrNoComponentType) {
+		t.Errorf("AddWatcher() without type error = %v, want ErrNoComponentType", err)
+	}
+	if err := AddWatcher[MockState](agg, "processor", watcher); err != nil {
+		t.Errorf("AddWatcher() with explicit type error = %v", err)
+	}
+	if agg.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", agg.Len())
+	}
+}
+
+func TestAggregator_Err_ReportsRejectedWatchers(t *testing.T) {
+	agg := NewAggregator()
+
+	if err := AddWatcher[MockState](agg, "worker", nil); !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("AddWatcher(nil) error = %v, want ErrNilWatcher", err)
+	}
+	if err := agg.AddSource(nil); !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("AddSource(nil) error = %v, want ErrNilWatcher", err)
+	}
+	var typedNil *MockTypedWatcher[MockState]
+	if err := AddWatcher[MockState](agg, "", typedNil); !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("AddWatcher(typed nil) error = %v, want ErrNilWatcher", err)
+	}
+
+	err := agg.Err()
+	if !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("Err() = %v, want ErrNilWatcher", err)
+	}
+	if agg.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", agg.Len())
+	}
+
+	if NewAggregator().Err() != nil {
+		t.Error("Err() on empty aggregator should be nil")
+	}
+}
+
+func TestAggregator_Snapshots_ClosesOnCancel(t *testing.T) {
+	agg := NewAggregator()
+	_ = AddWatcher[MockState](agg, "", NewMockTypedWatcher(MockState{}))
+	_ = AddWatcher[MockState](agg, "", NewMockTypedWatcher(MockState{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	snapshots := agg.Snapshots(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-snapshots:
+		if ok {
+			t.Error("Channel should be closed after context cancellation")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for channel close")
+	}
+}
+
+func TestAggregateSnapshots_MultipleSources(t *testing.T) {
+	w1 := NewMockTypedWatcher(MockState{})
+	w2 := NewMockTypedWatcher(MockState{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	snapshots := AggregateSnapshots(ctx,
+		NewWatcherAdapter[MockState]("worker", w1),
+		NewWatcherAdapter[MockState]("supervisor", w2),
+	)
+
+	w1.SendChange(StateChange[MockState]{ComponentID: "a"})
+	w2.SendChange(StateChange[MockState]{ComponentID: "b"})
+
+	seen := map[string]string{}
+	for len(seen) < 2 {
+		select {
+		case snap := <-snapshots:
+			seen[snap.ComponentID] = snap.ComponentType
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Timeout waiting for snapshots, got %v", seen)
+		}
+	}
+
+	if seen["a"] != "worker" || seen["b"] != "supervisor" {
+		t.Errorf("Unexpected component types: %v", seen)
+	}
+}
+
+// malformedWatcher is a Component whose Watch method does not return StateChange values.
+type malformedWatcher[T any] struct{ ch chan T }
+
+func (m malformedWatcher[T]) ComponentType() string { return "malformed" }
+
+func (m malformedWatcher[T]) Watch(context.Context) <-chan T { return m.ch }
+
+func TestAggregateWatchers_RejectsMalformedWatchers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type noState struct {
+		ComponentID string
+		Timestamp   time.Time
+	}
+	watchers := []interface{}{
+		(*MockTypedWatcher[MockState])(nil),
+		malformedWatcher[int]{make(chan int, 1)},
+		malformedWatcher[noState]{make(chan noState, 1)},
+		malformedWatcher[StateChange[MockState]]{}, // nil channel
+	}
+	for _, w := range watchers {
+		if _, _, err := watchByReflection(ctx, w); err == nil {
+			t.Errorf("watchByReflection(%T) accepted a malformed watcher", w)
+		}
+	}
+	watchers[1].(malformedWatcher[int]).ch <- 1
+	watchers[2].(malformedWatcher[noState]).ch <- noState{ComponentID: "x"}
+
+	select {
+	case snapshot, ok := <-AggregateWatchers(ctx, watchers...):
+		if ok {
+			t.Errorf("AggregateWatchers() delivered %+v from a malformed watcher", snapshot)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AggregateWatchers() did not close with only malformed watchers")
+	}
+}
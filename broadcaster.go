@@ -0,0 +1,189 @@
+package introspection
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultSubscriberBuffer is the default per-subscriber buffer size used by Broadcaster.
+const DefaultSubscriberBuffer = 16
+
+// Broadcaster is a reusable TypedWatcher implementation that fans out every
+// published state change to all active Watch subscribers.
+// Each subscriber has its own buffered channel, so concurrent callers of Watch
+// never steal each other's changes.
+//
+// Components typically embed or hold a Broadcaster and call Publish whenever
+// their state changes.
+type Broadcaster[S any] struct {
+	componentType string
+	componentID   string
+	options       *StreamOptions
+
+	pubMu  sync.Mutex // serializes Publish so every subscriber sees the same order
+	mu     sync.RWMutex
+	state  S
+	subs   map[*broadcastSub[S]]struct{}
+	closed bool
+
+	done      chan struct{} // closed by Close to release blocked publishers and watchers
+	closeOnce sync.Once
+}
+
+// broadcastSub is a single Watch subscription.
+type broadcastSub[S any] struct {
+	ch   chan StateChange[S]
+	done <-chan struct{}
+
+	mu     sync.Mutex // guards ch against being closed during a send
+	closed bool
+}
+
+// send delivers change unless the subscription is gone. It reports false if stop
+// was closed while waiting.
+func (s *broadcastSub[S]) send(change StateChange[S], stop <-chan struct{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	select {
+	case s.ch <- change:
+	case <-s.done:
+	case <-stop:
+		return false
+	}
+	return true
+}
+
+// close closes the subscription channel, waiting for a send in progress to give up.
+func (s *broadcastSub[S]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// NewBroadcaster creates a Broadcaster for the given component with an initial state.
+// By default each subscriber buffers DefaultSubscriberBuffer changes and a full buffer
+// delays Publish; use StreamOptions to change the buffer size or backpressure policy
+// so a slow subscriber never stalls the component.
+func NewBroadcaster[S any](componentType, componentID string, initial S, opts ...StreamOption) *Broadcaster[S] {
+	return &Broadcaster[S]{
+		componentType: componentType,
+		componentID:   componentID,
+		options:       newStreamOptions(DefaultSubscriberBuffer, opts),
+		state:         initial,
+		subs:          make(map[*broadcastSub[S]]struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// ComponentType returns the component type of the broadcaster.
+func (b *Broadcaster[S]) ComponentType() string {
+	return b.componentType
+}
+
+// ComponentID returns the component ID stamped on every published change.
+func (b *Broadcaster[S]) ComponentID() string {
+	return b.componentID
+}
+
+// State returns the most recently published state.
+func (b *Broadcaster[S]) State() S {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.state
+}
+
+// Watch returns a channel that receives every change published after the call.
+// The channel is closed when the provided context is cancelled or the broadcaster is closed.
+func (b *Broadcaster[S]) Watch(ctx context.Context) <-chan StateChange[S] {
+	sub := &broadcastSub[S]{
+		ch:   make(chan StateChange[S]),
+		done: ctx.Done(),
+	}
+	out := bufferStream(ctx, sub.ch, b.options, changeKey[S])
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(sub.ch)
+		return out
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-b.done:
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[sub]; ok {
+			delete(b.subs, sub)
+			sub.close()
+		}
+	}()
+
+	return out
+}
+
+// Publish records newState as the current state and delivers the change to every subscriber.
+// With the blocking policy, a subscriber whose buffer is full delays Publish until it
+// catches up or its context is cancelled.
+// Publish has no effect after Close.
+func (b *Broadcaster[S]) Publish(oldState, newState S) {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	change := StateChange[S]{
+		ComponentID:   b.componentID,
+		ComponentType: b.componentType,
+		OldState:      oldState,
+		NewState:      newState,
+		Timestamp:     time.Now(),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.state = newState
+	subs := make([]*broadcastSub[S], 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	// Send without holding mu, so a blocked subscriber cannot stall State or Watch.
+	for _, sub := range subs {
+		if !sub.send(change, b.done) {
+			return
+		}
+	}
+}
+
+// Close closes every subscriber channel. Subsequent calls to Watch return a closed channel.
+func (b *Broadcaster[S]) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		sub.close()
+	}
+}
@@ -0,0 +1,167 @@
+package introspection
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_Implements_Interfaces(t *testing.T) {
+	var _ TypedWatcher[MockState] = (*Broadcaster[MockState])(nil)
+	var _ Component = (*Broadcaster[MockState])(nil)
+}
+
+func TestBroadcaster_FanOut(t *testing.T) {
+	b := NewBroadcaster("processor", "proc-1", MockState{Value: "initial"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub1 := b.Watch(ctx)
+	sub2 := b.Watch(ctx)
+
+	b.Publish(MockState{Value: "initial"}, MockState{Value: "a"})
+	b.Publish(MockState{Value: "a"}, MockState{Value: "b"})
+
+	for i, sub := range []<-chan StateChange[MockState]{sub1, sub2} {
+		for _, want := range []string{"a", "b"} {
+			select {
+			case change := <-sub:
+				if change.NewState.Value != want {
+					t.Errorf("subscriber %d: NewState = %q, want %q", i, change.NewState.Value, want)
+				}
+				if change.ComponentID != "proc-1" || change.ComponentType != "processor" {
+					t.Errorf("subscriber %d: unexpected identity %q/%q", i, change.ComponentType, change.ComponentID)
+				}
+				if change.Timestamp.IsZero() {
+					t.Errorf("subscriber %d: Timestamp not set", i)
+				}
+			case <-time.After(1 * time.Second):
+				t.Fatalf("subscriber %d: timeout waiting for %q", i, want)
+			}
+		}
+	}
+
+	if got := b.State().Value; got != "b" {
+		t.Errorf("State() = %q, want %q", got, "b")
+	}
+}
+
+func TestBroadcaster_Watch_ClosesOnCancel(t *testing.T) {
+	b := NewBroadcaster("processor", "proc-1", MockState{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub := b.Watch(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-sub:
+		if ok {
+			t.Error("Channel should be closed after context cancellation")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for channel close")
+	}
+
+	// Publishing with no subscribers must not block.
+	b.Publish(MockState{}, MockState{Value: "x"})
+}
+
+func TestBroadcaster_Close(t *testing.T) {
+	b := NewBroadcaster("processor", "proc-1", MockState{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub := b.Watch(ctx)
+
+	// Fill the subscriber buffer so the next Publish blocks.
+	for i := 0; i < DefaultSubscriberBuffer; i++ {
+		b.Publish(MockState{}, MockState{})
+	}
+	published := make(chan struct{})
+	go func() {
+		b.Publish(MockState{}, MockState{Value: "blocked"})
+		close(published)
+	}()
+
+	b.Close()
+	b.Close() // idempotent
+
+	select {
+	case <-published:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Close() did not release blocked Publish")
+	}
+
+	for range sub {
+	}
+
+	if _, ok := <-b.Watch(ctx); ok {
+		t.Error("Watch() after Close should return a closed channel")
+	}
+}
+
+func TestBroadcaster_BlockedPublishDoesNotHoldLock(t *testing.T) {
+	b := NewBroadcaster("processor", "proc-1", MockState{}, WithBufferSize(1))
+	defer b.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.Watch(ctx) // never read
+	// One change fills the buffer and one waits in the stream; the third blocks.
+	b.Publish(MockState{}, MockState{Value: "1"})
+	b.Publish(MockState{}, MockState{Value: "2"})
+	published := make(chan struct{})
+	go func() {
+		b.Publish(MockState{}, MockState{Value: "3"})
+		close(published)
+	}()
+	time.Sleep(20 * time.Millisecond) // let Publish block
+
+	done := make(chan struct{})
+	go func() {
+		b.State()
+		b.Watch(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("State() and Watch() blocked behind a blocked Publish")
+	}
+
+	cancel()
+	select {
+	case <-published:
+	case <-time.After(1 * time.Second):
+		t.Fatal("cancelling the subscriber did not release Publish")
+	}
+}
+
+func TestBroadcaster_WithRegistry(t *testing.T) {
+	b := NewBroadcaster("processor", "proc-1", MockState{})
+	r := NewRegistry()
+	if err := RegisterWatcher[MockState](r, b.ComponentID(), b); err != nil {
+		t.Fatalf("RegisterWatcher() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := r.Snapshots(ctx)
+	second := r.Snapshots(ctx)
+
+	// Give both subscriptions time to attach to the broadcaster.
+	time.Sleep(20 * time.Millisecond)
+	b.Publish(MockState{}, MockState{Value: "shared"})
+
+	for i, ch := range []<-chan StateSnapshot{first, second} {
+		select {
+		case snap := <-ch:
+			if snap.Payload.(MockState).Value != "shared" {
+				t.Errorf("subscription %d: unexpected payload %v", i, snap.Payload)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatalf("subscription %d: timeout waiting for snapshot", i)
+		}
+	}
+}
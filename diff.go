@@ -0,0 +1,356 @@
+package introspection
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ChangeKind describes how a value differs between two states.
+type ChangeKind int
+
+const (
+	// ChangeModified means the value exists in both states with different contents.
+	ChangeModified ChangeKind = iota
+
+	// ChangeAdded means the value only exists in the new state.
+	ChangeAdded
+
+	// ChangeRemoved means the value only exists in the old state.
+	ChangeRemoved
+)
+
+// String returns the kind name.
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeModified:
+		return "modified"
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// FieldChange is a single difference between two states, addressed by path.
+// Paths use Go syntax: "Status", "Metadata[region]", "Children[2].Status".
+// An empty path means the values differ at the root.
+type FieldChange struct {
+	Path string
+	Kind ChangeKind
+	Old  any // nil for ChangeAdded
+	New  any // nil for ChangeRemoved
+}
+
+// String formats the change for logs, e.g. "Children[2].Status: Running → Failed".
+func (c FieldChange) String() string {
+	var value string
+	switch c.Kind {
+	case ChangeAdded:
+		value = "+ " + formatDiffValue(c.New)
+	case ChangeRemoved:
+		value = "- " + formatDiffValue(c.Old)
+	default:
+		value = formatDiffValue(c.Old) + " → " + formatDiffValue(c.New)
+	}
+	if c.Path == "" {
+		return value
+	}
+	return c.Path + ": " + value
+}
+
+// FormatChanges formats changes one per line.
+func FormatChanges(changes []FieldChange) string {
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffStates compares two state values and returns their differences, ordered by
+// struct field declaration, sorted map key and slice index.
+//
+// Exported struct fields, maps, slices, arrays, pointers and interfaces are compared
+// recursively. Slices of structs with a unique, non-empty Name field (such as Children)
+// are matched by Name rather than by position, so inserting a child reports one
+// addition instead of shifting every later element; the path uses the element's index
+// in the new state (or the old state, for removals). Pointer cycles are followed only once.
+// Structs without exported fields, such as time.Time, are compared as a whole.
+func DiffStates(old, new any) []FieldChange {
+	d := &differ{visited: make(map[diffVisit]bool)}
+	d.diff("", reflect.ValueOf(old), reflect.ValueOf(new))
+	return d.changes
+}
+
+// DiffStateChange compares the old and new state of a change.
+func DiffStateChange[S any](change StateChange[S]) []FieldChange {
+	return DiffStates(change.OldState, change.NewState)
+}
+
+// diffVisit identifies a pair of references already being compared.
+type diffVisit struct {
+	old, new uintptr
+	typ      reflect.Type
+}
+
+type differ struct {
+	changes []FieldChange
+	visited map[diffVisit]bool
+}
+
+func (d *differ) add(path string, kind ChangeKind, old, new reflect.Value) {
+	d.changes = append(d.changes, FieldChange{Path: path, Kind: kind, Old: diffInterface(old), New: diffInterface(new)})
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return
+	case !a.IsValid() || !b.IsValid() || a.Type() != b.Type():
+		if !reflect.DeepEqual(diffInterface(a), diffInterface(b)) {
+			d.add(path, ChangeModified, a, b)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, ChangeModified, a, b)
+			}
+			return
+		}
+		if a.Kind() == reflect.Interface {
+			d.diff(path, a.Elem(), b.Elem())
+			return
+		}
+		if d.enter(a, b) {
+			d.diff(path, a.Elem(), b.Elem())
+			d.leave(a, b)
+		}
+
+	case reflect.Struct:
+		d.diffStruct(path, a, b)
+
+	case reflect.Map:
+		if d.enter(a, b) {
+			d.diffMap(path, a, b)
+			d.leave(a, b)
+		}
+
+	case reflect.Slice, reflect.Array:
+		// A non-empty slice can contain itself through an interface element.
+		if a.Kind() == reflect.Slice && a.Len() > 0 && b.Len() > 0 {
+			if !d.enter(a, b) {
+				return
+			}
+			defer d.leave(a, b)
+		}
+		if names, ok := elementNames(a); ok {
+			if newNames, ok := elementNames(b); ok {
+				d.diffByName(path, a, b, names, newNames)
+				return
+			}
+		}
+		d.diffByIndex(path, a, b)
+
+	case reflect.Func:
+		// Functions are not comparable.
+
+	default:
+		if !a.Equal(b) {
+			d.add(path, ChangeModified, a, b)
+		}
+	}
+}
+
+// enter marks the pair of references a, b as being compared. It reports false if
+// the pair is already on the current path, which means the values form a cycle.
+func (d *differ) enter(a, b reflect.Value) bool {
+	v := diffVisit{old: a.Pointer(), new: b.Pointer(), typ: a.Type()}
+	if d.visited[v] {
+		return false
+	}
+	d.visited[v] = true
+	return true
+}
+
+func (d *differ) leave(a, b reflect.Value) {
+	delete(d.visited, diffVisit{old: a.Pointer(), new: b.Pointer(), typ: a.Type()})
+}
+
+func (d *differ) diffStruct(path string, a, b reflect.Value) {
+	t := a.Type()
+	exported := false
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		exported = true
+		d.diff(joinFieldPath(path, field.Name), a.Field(i), b.Field(i))
+	}
+	if exported {
+		return
+	}
+
+	// Opaque structs are compared as a whole.
+	if ta, ok := a.Interface().(time.Time); ok {
+		if !ta.Equal(b.Interface().(time.Time)) {
+			d.add(path, ChangeModified, a, b)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+		d.add(path, ChangeModified, a, b)
+	}
+}
+
+func (d *differ) diffMap(path string, a, b reflect.Value) {
+	// Keys are matched by value; the printed form only labels the path, so keys
+	// that print alike, such as 1 and "1" in a map[any]T, stay distinct.
+	type mapKey struct {
+		name string
+		key  reflect.Value
+	}
+	seen := make(map[any]bool)
+	var keys []mapKey
+	for _, m := range []reflect.Value{a, b} {
+		iter := m.MapRange()
+		for iter.Next() {
+			k := iter.Key()
+			if !seen[k.Interface()] {
+				seen[k.Interface()] = true
+				keys = append(keys, mapKey{fmt.Sprint(k.Interface()), k})
+			}
+		}
+	}
+	slices.SortFunc(keys, func(x, y mapKey) int {
+		if c := strings.Compare(x.name, y.name); c != 0 {
+			return c
+		}
+		return strings.Compare(diffKeyType(x.key), diffKeyType(y.key))
+	})
+
+	for _, k := range keys {
+		va, vb := a.MapIndex(k.key), b.MapIndex(k.key)
+		elemPath := path + "[" + k.name + "]"
+		switch {
+		case !va.IsValid():
+			d.add(elemPath, ChangeAdded, reflect.Value{}, vb)
+		case !vb.IsValid():
+			d.add(elemPath, ChangeRemoved, va, reflect.Value{})
+		default:
+			d.diff(elemPath, va, vb)
+		}
+	}
+}
+
+// diffKeyType returns the dynamic type of a map key, to order keys that print alike.
+func diffKeyType(k reflect.Value) string {
+	return fmt.Sprintf("%T", k.Interface())
+}
+
+func (d *differ) diffByIndex(path string, a, b reflect.Value) {
+	for i := range max(a.Len(), b.Len()) {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= a.Len():
+			d.add(elemPath, ChangeAdded, reflect.Value{}, b.Index(i))
+		case i >= b.Len():
+			d.add(elemPath, ChangeRemoved, a.Index(i), reflect.Value{})
+		default:
+			d.diff(elemPath, a.Index(i), b.Index(i))
+		}
+	}
+}
+
+func (d *differ) diffByName(path string, a, b reflect.Value, oldNames, newNames []string) {
+	oldIndex := make(map[string]int, len(oldNames))
+	for i, name := range oldNames {
+		oldIndex[name] = i
+	}
+	matched := make(map[string]bool, len(newNames))
+
+	for i, name := range newNames {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		j, ok := oldIndex[name]
+		if !ok {
+			d.add(elemPath, ChangeAdded, reflect.Value{}, b.Index(i))
+			continue
+		}
+		matched[name] = true
+		d.diff(elemPath, a.Index(j), b.Index(i))
+	}
+	for j, name := range oldNames {
+		if !matched[name] {
+			d.add(fmt.Sprintf("%s[%d]", path, j), ChangeRemoved, a.Index(j), reflect.Value{})
+		}
+	}
+}
+
+// elementNames returns the Name field of every element of a slice or array of structs
+// (or pointers to structs). It reports false unless every name is non-empty and unique.
+func elementNames(v reflect.Value) ([]string, bool) {
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct && elem.Kind() != reflect.Interface {
+		return nil, false
+	}
+
+	names := make([]string, v.Len())
+	seen := make(map[string]bool, v.Len())
+	for i := range v.Len() {
+		e := v.Index(i)
+		for e.Kind() == reflect.Pointer || e.Kind() == reflect.Interface {
+			if e.IsNil() {
+				return nil, false
+			}
+			e = e.Elem()
+		}
+		if e.Kind() != reflect.Struct {
+			return nil, false
+		}
+		name := getStringField(e, "Name")
+		if name == "" || seen[name] {
+			return nil, false
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names, true
+}
+
+func joinFieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// diffInterface returns the value held by v, or nil if v is invalid or not exported.
+func diffInterface(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// formatDiffValue formats a changed value for display.
+func formatDiffValue(v any) string {
+	if v == nil || isNilableAndNil(reflect.ValueOf(v)) {
+		return "<nil>"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%+v", v)
+}
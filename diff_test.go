@@ -0,0 +1,156 @@
+package introspection
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type diffNode struct {
+	Name     string
+	Status   string
+	PID      int
+	Metadata map[string]string
+	Children []diffNode
+	started  bool // unexported fields are ignored
+}
+
+func TestDiffStates_Tree(t *testing.T) {
+	old := diffNode{
+		Name:   "root",
+		Status: "Running",
+		Metadata: map[string]string{
+			"region": "eu",
+			"zone":   "a",
+		},
+		Children: []diffNode{
+			{Name: "w1", Status: "Running"},
+			{Name: "w2", Status: "Running"},
+			{Name: "w3", Status: "Running", PID: 7},
+		},
+		started: true,
+	}
+	new := diffNode{
+		Name:   "root",
+		Status: "Running",
+		Metadata: map[string]string{
+			"region": "us",
+			"tier":   "gold",
+		},
+		Children: []diffNode{
+			{Name: "w0", Status: "Pending"},
+			{Name: "w1", Status: "Running"},
+			{Name: "w3", Status: "Failed", PID: 7},
+		},
+	}
+
+	got := FormatChanges(DiffStates(old, new))
+	want := strings.Join([]string{
+		"Metadata[region]: eu → us",
+		"Metadata[tier]: + gold",
+		"Metadata[zone]: - a",
+		"Children[0]: + {Name:w0 Status:Pending PID:0 Metadata:map[] Children:[] started:false}",
+		"Children[2].Status: Running → Failed",
+		"Children[1]: - {Name:w2 Status:Running PID:0 Metadata:map[] Children:[] started:false}",
+	}, "\n")
+	if got != want {
+		t.Errorf("DiffStates() =\n%s\nwant\n%s", got, want)
+	}
+
+	if changes := DiffStates(old, old); len(changes) != 0 {
+		t.Errorf("DiffStates(x, x) = %v, want none", changes)
+	}
+}
+
+func TestDiffStates_Kinds(t *testing.T) {
+	changes := DiffStates(diffNode{Children: []diffNode{{Name: "a"}}}, diffNode{Children: []diffNode{{Name: "a"}, {Name: "b"}}})
+	if len(changes) != 1 || changes[0].Kind != ChangeAdded || changes[0].Path != "Children[1]" || changes[0].Old != nil {
+		t.Errorf("added child = %+v", changes)
+	}
+
+	// Without unique names, slices are compared by position.
+	changes = DiffStates([]int{1, 2, 3}, []int{1, 5})
+	if FormatChanges(changes) != "[1]: 2 → 5\n[2]: - 3" {
+		t.Errorf("positional diff = %q", FormatChanges(changes))
+	}
+
+	// Root values of different types are a single modification.
+	changes = DiffStates("Running", 3)
+	if len(changes) != 1 || changes[0].String() != "Running → 3" {
+		t.Errorf("type change = %v", changes)
+	}
+
+	// Interfaces and pointers are followed; nil is reported as <nil>.
+	type wrapper struct {
+		Payload any
+		Next    *diffNode
+	}
+	changes = DiffStates(wrapper{Payload: "a"}, wrapper{Payload: "b", Next: &diffNode{Name: "n"}})
+	if got := FormatChanges(changes); !strings.HasPrefix(got, "Payload: a → b\nNext: <nil> → ") {
+		t.Errorf("wrapper diff = %q", got)
+	}
+
+	// Opaque structs such as time.Time are compared as a whole.
+	now := time.Now()
+	type stamped struct{ At time.Time }
+	if changes := DiffStates(stamped{now}, stamped{now.Round(0)}); len(changes) != 0 {
+		t.Errorf("equal times = %v", changes)
+	}
+	if changes := DiffStates(stamped{now}, stamped{now.Add(time.Second)}); len(changes) != 1 || changes[0].Path != "At" {
+		t.Errorf("different times = %v", changes)
+	}
+}
+
+func TestDiffStates_Cycle(t *testing.T) {
+	type node struct {
+		Name   string
+		Status string
+		Parent *node
+		Kids   []*node
+	}
+	build := func(status string) *node {
+		root := &node{Name: "root", Status: "Running"}
+		child := &node{Name: "c", Status: status, Parent: root}
+		root.Kids = []*node{child}
+		return root
+	}
+
+	changes := DiffStates(build("Running"), build("Stopped"))
+	if FormatChanges(changes) != "Kids[0].Status: Running → Stopped" {
+		t.Errorf("cyclic diff = %q", FormatChanges(changes))
+	}
+}
+
+func TestDiffStates_SliceCycle(t *testing.T) {
+	build := func(status string) []any {
+		s := []any{status, nil}
+		s[1] = s
+		return s
+	}
+
+	changes := DiffStates(build("Running"), build("Stopped"))
+	if FormatChanges(changes) != "[0]: Running → Stopped" {
+		t.Errorf("cyclic slice diff = %q", FormatChanges(changes))
+	}
+}
+
+func TestDiffStates_MapKeysPrintingAlike(t *testing.T) {
+	old := map[any]int{1: 1, "1": 2}
+	new := map[any]int{1: 10, "1": 20}
+
+	changes := DiffStates(old, new)
+	if len(changes) != 2 || changes[0].New != 10 || changes[1].New != 20 {
+		t.Errorf("changes = %+v, want both keys modified", changes)
+	}
+}
+
+func TestDiffStateChange(t *testing.T) {
+	change := StateChange[diffNode]{
+		OldState: diffNode{Name: "w", Status: "Idle", PID: 1},
+		NewState: diffNode{Name: "w", Status: "Busy", PID: 1},
+	}
+	changes := DiffStateChange(change)
+	if len(changes) != 1 || changes[0].Kind != ChangeModified || changes[0].Old != "Idle" || changes[0].New != "Busy" {
+		t.Errorf("DiffStateChange() = %+v", changes)
+	}
+}
@@ -66,9 +66,13 @@ All visualization is fully customizable through configuration:
 
 Combine state changes from multiple components:
 
-	watchers := []TypedWatcher[MyState]{component1, component2, component3}
-	snapshots := AggregateWatchers(ctx, watchers...)
-	for snapshot := range snapshots {
+	agg := NewAggregator()
+	AddWatcher(agg, "processor", component1)
+	AddWatcher(agg, "controller", component2)
+	if err := agg.Err(); err != nil {
+		// Handle rejected watchers
+	}
+	for snapshot := range agg.Snapshots(ctx) {
 		// Process state changes
 	}
 
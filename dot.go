@@ -0,0 +1,241 @@
+package introspection
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Graphviz DOT rendering of the generic diagrams.
+// The DOT functions build the same Graph as their Mermaid counterparts, so a
+// single configuration drives both outputs: NodeStyleFunc shapes are mapped to
+// DOT shapes and the Mermaid classDef lines (DefaultStyles or WithStyles) are
+// mapped to DOT colors.
+
+// TreeDOT returns a Graphviz DOT digraph representing a hierarchical tree structure.
+// It is the DOT equivalent of TreeDiagram.
+func TreeDOT(root any, config *DiagramConfig, opts ...MermaidOption) string {
+	return DOTRenderer{}.Render(BuildTreeGraph(root, config, opts...))
+}
+
+// ComponentDOT renders a topology of two components as a Graphviz DOT digraph.
+// It is the DOT equivalent of ComponentDiagram.
+func ComponentDOT(primary, secondary any, config *DiagramConfig, opts ...MermaidOption) string {
+	return DOTRenderer{}.Render(BuildComponentGraph(primary, secondary, config, opts...))
+}
+
+// StateMachineDOT renders the state machine of StateMachineDiagram as a Graphviz DOT digraph.
+// Notes from NoteGenerator are drawn as note-shaped nodes attached to the graceful state.
+func StateMachineDOT(state any, config *StateMachineConfig, opts ...MermaidOption) string {
+	return DOTRenderer{}.Render(BuildStateMachineGraph(state, config, opts...))
+}
+
+// DOTRenderer renders a Graph as a Graphviz DOT digraph.
+// Subgraphs become clusters and notes become note-shaped nodes.
+type DOTRenderer struct{}
+
+// Render implements Renderer.
+func (r DOTRenderer) Render(g *Graph) string {
+	classes := parseClassDefs(g.Styles)
+
+	rankdir := "TB"
+	if g.Kind == StateGraph {
+		rankdir = "LR"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph G {\n")
+	sb.WriteString(fmt.Sprintf("    rankdir=%s;\n", rankdir))
+	if len(g.Subgraphs) > 0 {
+		sb.WriteString("    compound=true;\n")
+	}
+	sb.WriteString("    node [fontname=\"Helvetica\"];\n")
+
+	r.renderSubgraphs(&sb, g.Kind, g.Subgraphs, classes, "    ")
+	r.renderBody(&sb, g.Kind, g.Nodes, g.Edges, classes, "    ")
+
+	for i, note := range g.Notes {
+		lines := strings.Split(strings.TrimRight(note.Text, "\n"), "\n")
+		for j := range lines {
+			lines[j] = strings.TrimSpace(lines[j])
+		}
+		id := fmt.Sprintf("__note_%d", i)
+		sb.WriteString(fmt.Sprintf("    %s [shape=note, label=%s];\n", id, dotQuote(strings.Join(lines, "\n"))))
+		sb.WriteString(fmt.Sprintf("    %s -> %s [style=dashed, arrowhead=none];\n", dotID(note.Target), id))
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (r DOTRenderer) renderSubgraphs(sb *strings.Builder, kind GraphKind, subgraphs []*Subgraph, classes map[string]map[string]string, indent string) {
+	for _, sg := range subgraphs {
+		sb.WriteString(fmt.Sprintf("%ssubgraph cluster_%s {\n", indent, dotID(sg.ID)))
+		sb.WriteString(fmt.Sprintf("%s    label=%s;\n", indent, dotQuote(sg.Label)))
+		r.renderSubgraphs(sb, kind, sg.Subgraphs, classes, indent+"    ")
+		r.renderBody(sb, kind, sg.Nodes, sg.Edges, classes, indent+"    ")
+		sb.WriteString(indent + "}\n")
+	}
+}
+
+func (r DOTRenderer) renderBody(sb *strings.Builder, kind GraphKind, nodes []*Node, edges []*Edge, classes map[string]map[string]string, indent string) {
+	for _, n := range nodes {
+		var attrs []string
+		switch {
+		case isStartState(n.ID):
+			attrs = []string{"shape=point", "width=0.2"}
+		case isEndState(n.ID):
+			attrs = []string{"shape=doublecircle", "label=\"\"", "width=0.15"}
+		case kind == StateGraph:
+			attrs = []string{"shape=box", "style=rounded", "label=" + dotQuote(dotLabel(n.Label))}
+		default:
+			attrs = append(dotShape(n.ShapeStart, n.ShapeEnd), "label="+dotQuote(dotLabel(n.Label)))
+		}
+		attrs = mergeDOTStyle(append(attrs, dotClassAttrs(classes, n.Classes...)...))
+		sb.WriteString(fmt.Sprintf("%s%s [%s];\n", indent, dotID(n.ID), strings.Join(attrs, ", ")))
+	}
+
+	for _, e := range edges {
+		var attrs []string
+		if e.Label != "" {
+			attrs = append(attrs, "label="+dotQuote(e.Label))
+		}
+		if e.Back {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			sb.WriteString(fmt.Sprintf("%s%s -> %s [%s];\n", indent, dotID(e.From), dotID(e.To), strings.Join(attrs, ", ")))
+		} else {
+			sb.WriteString(fmt.Sprintf("%s%s -> %s;\n", indent, dotID(e.From), dotID(e.To)))
+		}
+	}
+}
+
+// dotShape maps Mermaid node shape delimiters to DOT shape attributes.
+func dotShape(shapeStart, shapeEnd string) []string {
+	switch shapeStart + shapeEnd {
+	case "{{}}":
+		return []string{"shape=hexagon"}
+	case "[[]]":
+		return []string{"shape=box", "peripheries=2"}
+	case "()", "([])":
+		return []string{"shape=box", "style=rounded"}
+	case "(())":
+		return []string{"shape=circle"}
+	case "{}":
+		return []string{"shape=diamond"}
+	case "[()]":
+		return []string{"shape=cylinder"}
+	case ">]":
+		return []string{"shape=cds"}
+	default:
+		return []string{"shape=box"}
+	}
+}
+
+// dotClassAttrs converts Mermaid classDef properties to DOT attributes.
+// Classes are applied in order, so later classes override earlier ones,
+// matching Mermaid where the status class is applied after the shape class.
+func dotClassAttrs(classes map[string]map[string]string, names ...string) []string {
+	merged := map[string]string{}
+	for _, name := range names {
+		for k, v := range classes[name] {
+			merged[k] = v
+		}
+	}
+
+	var attrs []string
+	if fill, ok := merged["fill"]; ok {
+		attrs = append(attrs, "style=filled", "fillcolor="+dotQuote(fill))
+	}
+	if stroke, ok := merged["stroke"]; ok {
+		attrs = append(attrs, "color="+dotQuote(stroke))
+	}
+	if color, ok := merged["color"]; ok {
+		attrs = append(attrs, "fontcolor="+dotQuote(color))
+	}
+	if width, ok := merged["stroke-width"]; ok {
+		attrs = append(attrs, "penwidth="+strings.TrimSuffix(width, "px"))
+	}
+	if dash, ok := merged["stroke-dasharray"]; ok && dash != "0" {
+		attrs = append(attrs, "style=dashed")
+	}
+	return attrs
+}
+
+// mergeDOTStyle combines repeated style attributes into a single comma-separated style.
+func mergeDOTStyle(attrs []string) []string {
+	var styles []string
+	result := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		if value, ok := strings.CutPrefix(attr, "style="); ok {
+			styles = append(styles, strings.Split(value, ",")...)
+			continue
+		}
+		result = append(result, attr)
+	}
+	if len(styles) > 0 {
+		result = append(result, "style="+dotQuote(strings.Join(styles, ",")))
+	}
+	return result
+}
+
+// parseClassDefs extracts Mermaid classDef lines into class name -> property -> value.
+func parseClassDefs(styles string) map[string]map[string]string {
+	classes := map[string]map[string]string{}
+	for _, line := range strings.Split(styles, "\n") {
+		line = strings.TrimSpace(line)
+		rest, ok := strings.CutPrefix(line, "classDef ")
+		if !ok {
+			continue
+		}
+		name, props, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		props = strings.TrimSuffix(strings.TrimSpace(props), ";")
+
+		class := map[string]string{}
+		for _, prop := range strings.Split(props, ",") {
+			k, v, ok := strings.Cut(prop, ":")
+			if !ok {
+				continue
+			}
+			class[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		for _, n := range strings.Split(name, ",") {
+			classes[n] = class
+		}
+	}
+	return classes
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+	dotIDChar = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// dotLabel converts a Mermaid HTML label to plain text with newlines. Only the tags
+// the diagram builders emit are removed; any other text, including user-supplied
+// angle brackets, is kept.
+func dotLabel(label string) string {
+	return mermaidMarkup.ReplaceAllStringFunc(label, func(tag string) string {
+		if strings.HasPrefix(tag, "<br") {
+			return "\n"
+		}
+		return ""
+	})
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// dotID returns id as a DOT identifier, quoting it if necessary.
+func dotID(id string) string {
+	if id != "" && !dotIDChar.MatchString(id) && (id[0] < '0' || id[0] > '9') {
+		return id
+	}
+	return dotQuote(id)
+}
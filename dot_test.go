@@ -0,0 +1,174 @@
+package introspection
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTreeDOT_ShapesAndClasses(t *testing.T) {
+	type TaskState struct {
+		Name     string
+		Status   string
+		PID      int
+		Metadata map[string]string
+		Children []TaskState
+	}
+
+	root := TaskState{
+		Name:     "scheduler",
+		Status:   "Running",
+		Metadata: map[string]string{"type": "manager"},
+		Children: []TaskState{
+			{Name: "task-1", Status: "Failed", PID: 2001, Metadata: map[string]string{"type": "task"}},
+			{Name: "fn", Status: "Running", Metadata: map[string]string{"type": "goroutine"}},
+		},
+	}
+
+	config := DefaultDiagramConfig()
+	config.SecondaryID = "root"
+
+	dot := TreeDOT(root, config)
+
+	expected := []string{
+		"digraph G {",
+		`root [shape=hexagon, label="🧠 scheduler\nStatus: Running"`,
+		`fillcolor="#d1ecf1"`,
+		`root_0 [shape=box, label="⚙️ task-1\nStatus: Failed\nPID: 2001"`,
+		`fillcolor="#f8d7da"`,
+		`penwidth=2`,
+		`style="rounded,filled,dashed"`,
+		"root -> root_0;",
+		"root -> root_1;",
+	}
+	for _, want := range expected {
+		if !strings.Contains(dot, want) {
+			t.Errorf("TreeDOT() missing %q\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, "<b>") || strings.Contains(dot, "<br/>") {
+		t.Error("TreeDOT() should strip HTML from labels")
+	}
+}
+
+func TestTreeDOT_CustomStylesAndStyler(t *testing.T) {
+	type Node struct {
+		Name   string
+		Status string
+	}
+
+	config := DefaultDiagramConfig()
+	config.SecondaryID = "n"
+	config.NodeStyler = func(map[string]string) (string, string, string, string) {
+		return "*", "[(", ")]", "storage"
+	}
+
+	dot := TreeDOT(Node{Name: "db", Status: "Running"}, config,
+		WithStyles("classDef storage stroke:#123456;\nclassDef running fill:#abcdef;"))
+
+	for _, want := range []string{`shape=cylinder`, `color="#123456"`, `fillcolor="#abcdef"`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("TreeDOT() missing %q\n%s", want, dot)
+		}
+	}
+}
+
+func TestComponentDOT_Clusters(t *testing.T) {
+	type Controller struct {
+		Enabled  bool
+		Stopping bool
+		Reason   string
+	}
+	type Process struct {
+		Name   string
+		Status string
+	}
+
+	config := DefaultDiagramConfig()
+	config.PrimaryID = "ctrl"
+	config.PrimaryLabel = "Control \"Layer\""
+	config.SecondaryID = "proc"
+	config.SecondaryLabel = "Process Layer"
+	config.ConnectionLabel = "coordinates"
+
+	dot := ComponentDOT(Controller{Enabled: true, Stopping: true, Reason: "drain"}, Process{Name: "main", Status: "Running"}, config)
+
+	expected := []string{
+		"subgraph cluster_ctrl {",
+		`label="Control \"Layer\"";`,
+		"subgraph cluster_proc {",
+		`label="Process Layer";`,
+		`ctrl [shape=box, label="⚡ Component\nMode: Stopping\nReason: drain"`,
+		`fillcolor="#eef2ff"`,
+		`ctrl -> proc [label="coordinates"];`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(dot, want) {
+			t.Errorf("ComponentDOT() missing %q\n%s", want, dot)
+		}
+	}
+}
+
+func TestStateMachineDOT(t *testing.T) {
+	type ServiceState struct {
+		ForceExitThreshold int
+		Stopping           bool
+	}
+
+	config := DefaultStateMachineConfig()
+	config.InitialState = "Active"
+	config.GracefulState = "Draining"
+	config.ForcedState = "Killed"
+	config.NoteGenerator = func(any) string {
+		return "        Draining tasks\n        Timeout: 5s\n"
+	}
+
+	dot := StateMachineDOT(ServiceState{ForceExitThreshold: 3, Stopping: true}, config)
+
+	expected := []string{
+		"__start -> Active;",
+		`Active -> Draining [label="Interrupt"];`,
+		`Draining -> Killed [label="Force x3"];`,
+		`Killed -> __end [label="Exit"];`,
+		`Draining -> __end [label="Complete"];`,
+		`__note_0 [shape=note, label="Draining tasks\nTimeout: 5s"];`,
+		`Draining [shape=box, label="Draining", fillcolor="#f8d7da"`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(dot, want) {
+			t.Errorf("StateMachineDOT() missing %q\n%s", want, dot)
+		}
+	}
+
+	// Without a threshold, the forced state is omitted.
+	dot = StateMachineDOT(ServiceState{}, nil)
+	if strings.Contains(dot, "ForceExit") {
+		t.Error("StateMachineDOT() should omit the forced state without ForceExitThreshold")
+	}
+}
+
+func TestDOTID_Quoting(t *testing.T) {
+	tests := map[string]string{
+		"root_0":  "root_0",
+		"my node": `"my node"`,
+		"0start":  `"0start"`,
+		`a"b`:     `"a\"b"`,
+		"":        `""`,
+	}
+	for in, want := range tests {
+		if got := dotID(in); got != want {
+			t.Errorf("dotID(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestTreeDOT_KeepsUserMarkup(t *testing.T) {
+	type Node struct {
+		Name   string
+		Status string
+	}
+
+	dot := TreeDOT(Node{Name: `a"b<script>x</script>`, Status: "a < b"}, nil)
+	if want := `label="⚙️ a\"b<script>x</script>\nStatus: a < b"`; !strings.Contains(dot, want) {
+		t.Errorf("TreeDOT() missing %q\n%s", want, dot)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aretw0/introspection"
+	"github.com/aretw0/introspection/httpx"
+)
+
+// Example serving a live dashboard for a task pool whose tasks fail and restart.
+// Open http://localhost:8080/debug/introspection/ in a browser.
+
+// PoolState represents the controller of the task pool
+type PoolState struct {
+	Enabled  bool
+	Stopping bool
+	Stopped  bool
+	Reason   string
+}
+
+// TaskState represents a task in the pool
+type TaskState struct {
+	Name     string
+	Status   string
+	PID      int
+	Metadata map[string]string
+	Children []TaskState
+}
+
+// TaskEvent is emitted whenever a task changes status
+type TaskEvent struct {
+	ID     string
+	Status string
+	At     time.Time
+}
+
+func (e TaskEvent) ComponentID() string   { return e.ID }
+func (e TaskEvent) ComponentType() string { return "task" }
+func (e TaskEvent) Timestamp() time.Time  { return e.At }
+func (e TaskEvent) EventType() string     { return e.Status }
+
+// EventLog fans out task events to every connected dashboard
+type EventLog struct {
+	*introspection.Broadcaster[TaskEvent]
+}
+
+func (l EventLog) Events(ctx context.Context) <-chan introspection.ComponentEvent {
+	out := make(chan introspection.ComponentEvent)
+	go func() {
+		defer close(out)
+		for change := range l.Watch(ctx) {
+			select {
+			case out <- change.NewState:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// introspectable exposes a Broadcaster's state through the Introspectable interface
+type introspectable[S any] struct {
+	*introspection.Broadcaster[S]
+}
+
+func (i introspectable[S]) State() any {
+	return i.Broadcaster.State()
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pool := introspection.NewBroadcaster("pool", "pool-1", PoolState{Enabled: true})
+	tasks := introspection.NewBroadcaster("tasks", "pool-1", TaskState{
+		Name:     "Pool",
+		Status:   "Running",
+		Metadata: map[string]string{"type": "manager"},
+		Children: []TaskState{
+			{Name: "ingest", Status: "Running", PID: 101, Metadata: map[string]string{"type": "task"}},
+			{Name: "transform", Status: "Running", PID: 102, Metadata: map[string]string{"type": "task"}},
+			{Name: "export", Status: "Running", PID: 103, Metadata: map[string]string{"type": "task"}},
+		},
+	})
+	events := EventLog{introspection.NewBroadcaster("events", "pool-1", TaskEvent{})}
+
+	registry := introspection.NewRegistry(introspection.WithBackpressure(introspection.BackpressureCoalesce))
+	_ = introspection.RegisterWatcher[PoolState](registry, "pool-1", pool)
+	_ = introspection.RegisterWatcher[TaskState](registry, "pool-1", tasks)
+
+	store := introspection.NewStateStore()
+	go store.Consume(ctx, registry.Snapshots(ctx))
+
+	config := introspection.DefaultDiagramConfig()
+	config.PrimaryLabel = "Pool Controller"
+	config.PrimaryNodeLabel = "🎛️ Pool"
+	config.SecondaryLabel = "Tasks"
+	config.ConnectionLabel = "runs"
+
+	handler := httpx.NewHandler(
+		httpx.WithStore(store),
+		httpx.WithSnapshots(registry),
+		httpx.WithEvents(events),
+		httpx.WithDiagram("topology", httpx.Components(introspectable[PoolState]{pool}, introspectable[TaskState]{tasks}, config)),
+		httpx.WithDiagram("tasks", httpx.Tree(introspectable[TaskState]{tasks}, config)),
+		httpx.WithDashboardTitle("Task Pool"),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/introspection/", http.StripPrefix("/debug/introspection", handler))
+	server := &http.Server{Addr: ":8080", Handler: mux}
+
+	go simulate(ctx, tasks, events)
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	fmt.Println("Dashboard: http://localhost:8080/debug/introspection/")
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// simulate randomly fails and restarts tasks
+func simulate(ctx context.Context, tasks *introspection.Broadcaster[TaskState], events EventLog) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		oldState := tasks.State()
+		newState := oldState
+		newState.Children = append([]TaskState(nil), oldState.Children...)
+
+		i := rand.Intn(len(newState.Children))
+		child := newState.Children[i]
+		if child.Status == "Running" {
+			child.Status = "Failed"
+		} else {
+			child.Status = "Running"
+			child.PID += 100
+		}
+		newState.Children[i] = child
+
+		tasks.Publish(oldState, newState)
+		events.Publish(TaskEvent{}, TaskEvent{ID: child.Name, Status: child.Status, At: time.Now()})
+	}
+}
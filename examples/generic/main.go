@@ -32,62 +32,25 @@ type TaskState struct {
 
 // Scheduler manages tasks
 type Scheduler struct {
-	id    string
-	state SchedulerState
-	ch    chan introspection.StateChange[SchedulerState]
+	*introspection.Broadcaster[SchedulerState]
 }
 
 func NewScheduler(id string) *Scheduler {
 	return &Scheduler{
-		id: id,
-		state: SchedulerState{
+		Broadcaster: introspection.NewBroadcaster("scheduler", id, SchedulerState{
 			Enabled:            true,
 			ForceExitThreshold: 2,
 			HookTimeout:        10 * time.Second,
-		},
-		ch: make(chan introspection.StateChange[SchedulerState], 10),
+		}),
 	}
 }
 
-func (s *Scheduler) ComponentType() string {
-	return "scheduler"
-}
-
-func (s *Scheduler) State() SchedulerState {
-	return s.state
-}
-
-func (s *Scheduler) Watch(ctx context.Context) <-chan introspection.StateChange[SchedulerState] {
-	out := make(chan introspection.StateChange[SchedulerState])
-	go func() {
-		defer close(out)
-		for {
-			select {
-			case change := <-s.ch:
-				select {
-				case out <- change:
-				case <-ctx.Done():
-					return
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-	return out
-}
-
 func (s *Scheduler) Shutdown() {
-	oldState := s.state
-	s.state.Stopping = true
-	s.state.Reason = "Scheduled Shutdown"
-	s.ch <- introspection.StateChange[SchedulerState]{
-		ComponentID:   s.id,
-		ComponentType: "scheduler",
-		OldState:      oldState,
-		NewState:      s.state,
-		Timestamp:     time.Now(),
-	}
+	oldState := s.State()
+	newState := oldState
+	newState.Stopping = true
+	newState.Reason = "Scheduled Shutdown"
+	s.Publish(oldState, newState)
 }
 
 func main() {
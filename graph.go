@@ -0,0 +1,588 @@
+package introspection
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GraphKind distinguishes the diagram families a Graph can describe.
+type GraphKind int
+
+const (
+	// FlowchartGraph is a node/edge topology (TreeDiagram, ComponentDiagram).
+	FlowchartGraph GraphKind = iota
+
+	// StateGraph is a state machine (StateMachineDiagram).
+	StateGraph
+)
+
+// Pseudo-state IDs used by state graphs for the initial and final states.
+// Mermaid renders both as [*]. Composite states use these IDs as a prefix
+// (e.g. "__start_Parent") so that each scope has its own pseudo-states.
+const (
+	StartStateID = "__start"
+	EndStateID   = "__end"
+)
+
+// Graph is a format-independent diagram model built once from component state.
+// Renderers turn it into text (Mermaid, DOT, ...), and callers can inspect or
+// post-process it (filter or annotate nodes) before rendering.
+type Graph struct {
+	Kind      GraphKind
+	Nodes     []*Node     // Nodes outside any subgraph
+	Edges     []*Edge     // Edges outside any subgraph
+	Subgraphs []*Subgraph // Grouped nodes (flowchart subgraphs, composite states)
+	Notes     []*Note     // Annotations attached to nodes
+	Styles    string      // Mermaid classDef definitions; other renderers derive colors from them
+}
+
+// Node is a single diagram node.
+type Node struct {
+	ID         string
+	Label      string // May contain the HTML subset Mermaid accepts (<b>, <br/>)
+	ShapeStart string // Mermaid shape delimiters, e.g. "{{" and "}}" for a hexagon
+	ShapeEnd   string
+
+	// Classes lists the node's style classes. The Mermaid renderer attaches the
+	// first one inline (:::class) and the rest with class statements.
+	Classes []string
+
+	// Fields extracted from the state the node was built from.
+	Name     string
+	Status   string
+	PID      int
+	Metadata map[string]string
+	Data     any // The original state value
+}
+
+// Edge connects two nodes by ID.
+type Edge struct {
+	From  string
+	To    string
+	Label string
+	Back  bool // Points back to an already drawn node (a cycle or shared child); drawn dashed
+}
+
+// Subgraph groups nodes and the edges between them.
+// In state graphs a subgraph is a composite state.
+type Subgraph struct {
+	ID        string // Logical ID; renderers may decorate it (Mermaid appends "_graph", DOT prepends "cluster_")
+	Label     string
+	Nodes     []*Node
+	Edges     []*Edge
+	Subgraphs []*Subgraph // Nested subgraphs
+}
+
+// Note attaches free text to a node.
+type Note struct {
+	Target   string // Node ID
+	Position string // "right of" or "left of"
+	Text     string // Raw note body; lines may carry their own indentation
+}
+
+// Renderer turns a Graph into diagram source text.
+type Renderer interface {
+	Render(g *Graph) string
+}
+
+// AllNodes returns every node in the graph, including nodes inside subgraphs.
+func (g *Graph) AllNodes() []*Node {
+	nodes := append([]*Node(nil), g.Nodes...)
+	for _, sg := range g.AllSubgraphs() {
+		nodes = append(nodes, sg.Nodes...)
+	}
+	return nodes
+}
+
+// AllEdges returns every edge in the graph, including edges inside subgraphs.
+func (g *Graph) AllEdges() []*Edge {
+	var edges []*Edge
+	for _, sg := range g.AllSubgraphs() {
+		edges = append(edges, sg.Edges...)
+	}
+	return append(edges, g.Edges...)
+}
+
+// AllSubgraphs returns every subgraph in the graph, including nested ones, in depth-first order.
+func (g *Graph) AllSubgraphs() []*Subgraph {
+	var subgraphs []*Subgraph
+	var visit func([]*Subgraph)
+	visit = func(sgs []*Subgraph) {
+		for _, sg := range sgs {
+			subgraphs = append(subgraphs, sg)
+			visit(sg.Subgraphs)
+		}
+	}
+	visit(g.Subgraphs)
+	return subgraphs
+}
+
+// Node returns the node with the given ID, or nil.
+func (g *Graph) Node(id string) *Node {
+	for _, n := range g.AllNodes() {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// RemoveNodes deletes every node for which remove returns true,
+// together with the edges and notes that reference it.
+func (g *Graph) RemoveNodes(remove func(*Node) bool) {
+	removed := map[string]bool{}
+
+	filterNodes := func(nodes []*Node) []*Node {
+		kept := nodes[:0]
+		for _, n := range nodes {
+			if remove(n) {
+				removed[n.ID] = true
+				continue
+			}
+			kept = append(kept, n)
+		}
+		return kept
+	}
+	filterEdges := func(edges []*Edge) []*Edge {
+		kept := edges[:0]
+		for _, e := range edges {
+			if !removed[e.From] && !removed[e.To] {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	}
+
+	subgraphs := g.AllSubgraphs()
+
+	g.Nodes = filterNodes(g.Nodes)
+	for _, sg := range subgraphs {
+		sg.Nodes = filterNodes(sg.Nodes)
+	}
+
+	g.Edges = filterEdges(g.Edges)
+	for _, sg := range subgraphs {
+		sg.Edges = filterEdges(sg.Edges)
+	}
+
+	notes := g.Notes[:0]
+	for _, n := range g.Notes {
+		if !removed[n.Target] {
+			notes = append(notes, n)
+		}
+	}
+	g.Notes = notes
+}
+
+// BuildTreeGraph builds the graph rendered by TreeDiagram.
+// The structure is introspected via reflection using common field names (Name, Status, PID, Metadata, Children).
+func BuildTreeGraph(root any, config *DiagramConfig, opts ...MermaidOption) *Graph {
+	if config == nil {
+		config = DefaultDiagramConfig()
+	}
+	options := newMermaidOptions(opts)
+
+	g := &Graph{Kind: FlowchartGraph, Styles: options.Styles}
+	ids := newNodeIDs(config)
+	g.Nodes, g.Edges = buildTree(root, ids.unique(config.SecondaryID), config, ids)
+	return g
+}
+
+// BuildComponentGraph builds the graph rendered by ComponentDiagram:
+// a primary and a secondary subgraph joined by a labeled edge.
+func BuildComponentGraph(primary, secondary any, config *DiagramConfig, opts ...MermaidOption) *Graph {
+	if config == nil {
+		config = DefaultDiagramConfig()
+	}
+	options := newMermaidOptions(opts)
+
+	ids := newNodeIDs(config)
+	primaryID, secondaryID := ids.subgraph(config.PrimaryID), ids.subgraph(config.SecondaryID)
+	treeNodes, treeEdges := buildTree(secondary, secondaryID, config, ids)
+
+	return &Graph{
+		Kind: FlowchartGraph,
+		Subgraphs: []*Subgraph{
+			{
+				ID:    primaryID,
+				Label: config.PrimaryLabel,
+				Nodes: []*Node{buildFragment(primary, primaryID, config.PrimaryNodeLabel, config)},
+			},
+			{
+				ID:    secondaryID,
+				Label: config.SecondaryLabel,
+				Nodes: treeNodes,
+				Edges: treeEdges,
+			},
+		},
+		Edges:  []*Edge{{From: primaryID, To: secondaryID, Label: config.ConnectionLabel}},
+		Styles: options.Styles,
+	}
+}
+
+// BuildStateMachineGraph builds the graph rendered by StateMachineDiagram.
+// It introspects the state object via reflection to find relevant fields.
+func BuildStateMachineGraph(state any, config *StateMachineConfig, opts ...MermaidOption) *Graph {
+	if config == nil {
+		config = DefaultStateMachineConfig()
+	}
+	options := newMermaidOptions(opts)
+
+	v := reflect.ValueOf(state)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	forceExitThreshold := getIntField(v, "ForceExitThreshold")
+	stopping := boolValue(mappedField(v, fieldStopping, ""))
+	stopped := boolValue(mappedField(v, fieldStopped, ""))
+
+	g := &Graph{Kind: StateGraph, Styles: options.Styles}
+
+	ids := newStateIDs()
+	initialID, gracefulID := ids.state(config.InitialState), ids.state(config.GracefulState)
+	start := &Node{ID: StartStateID}
+	end := &Node{ID: EndStateID}
+	initial := &Node{ID: initialID, Label: config.InitialState, Name: config.InitialState}
+	graceful := &Node{ID: gracefulID, Label: config.GracefulState, Name: config.GracefulState}
+
+	g.Nodes = []*Node{start, initial, graceful}
+	g.Edges = []*Edge{
+		{From: StartStateID, To: initialID},
+		{From: initialID, To: gracefulID, Label: config.InitialToGraceful},
+	}
+
+	if config.NoteGenerator != nil {
+		if note := config.NoteGenerator(state); note != "" {
+			g.Notes = append(g.Notes, &Note{Target: gracefulID, Position: "right of", Text: note})
+		}
+	}
+
+	if forceExitThreshold > 0 {
+		forcedID := ids.state(config.ForcedState)
+		g.Nodes = append(g.Nodes, &Node{ID: forcedID, Label: config.ForcedState, Name: config.ForcedState})
+		g.Edges = append(g.Edges,
+			&Edge{From: gracefulID, To: forcedID, Label: fmt.Sprintf("%s x%d", config.GracefulToForced, forceExitThreshold)},
+			&Edge{From: forcedID, To: EndStateID, Label: "Exit"},
+		)
+	}
+
+	g.Edges = append(g.Edges, &Edge{From: gracefulID, To: EndStateID, Label: config.GracefulToFinal})
+	g.Nodes = append(g.Nodes, end)
+
+	// Highlight the current state
+	if stopped {
+		end.Classes = []string{"stopped"}
+	} else if stopping {
+		graceful.Classes = []string{"stopping"}
+	} else {
+		initial.Classes = []string{"running"}
+	}
+
+	return g
+}
+
+// buildFragment builds a single component node (for primary/controller type components).
+// If the config provides a styler and labeler, uses them. Otherwise, uses default
+// reflection-based behavior honoring config.Fields.
+func buildFragment(comp any, id, labelPrefix string, config *DiagramConfig) *Node {
+	primary := readPrimaryFields(comp, config.Fields)
+
+	statusClass := primary.class()
+	if styler := config.PrimaryNodeStyler; styler != nil {
+		statusClass = styler(comp)
+	}
+	labelContent := primary.label()
+	if labeler := config.PrimaryNodeLabeler; labeler != nil {
+		labelContent = labeler(comp)
+	}
+
+	return &Node{
+		ID:         id,
+		Label:      fmt.Sprintf("<b>%s</b><br/>%s", labelPrefix, labelContent),
+		ShapeStart: "[",
+		ShapeEnd:   "]",
+		Classes:    []string{"signal", statusClass},
+		Name:       labelPrefix,
+		Data:       comp,
+	}
+}
+
+// buildTree builds the nodes and edges of a hierarchical tree structure in depth-first order.
+// A child that was already drawn, because of a cycle or a shared pointer child,
+// gets a back edge to its existing node instead of being visited again.
+func buildTree(root any, rootID string, config *DiagramConfig, ids *nodeIDs) ([]*Node, []*Edge) {
+	var nodes []*Node
+	var edges []*Edge
+	visits := newTreeVisits(true)
+
+	var visit func(node any, t treeFields, id string)
+	visit = func(node any, t treeFields, id string) {
+		self, kids := t.visitKeys()
+		visits.enter(self, kids, id)
+		defer visits.leave(self, kids)
+		nodes = append(nodes, newTreeNode(id, t, node, config))
+
+		for i, child := range t.children {
+			ct := readTreeNode(child, config.Fields)
+			if existing, seen := visits.seen(ct.visitKeys()); seen {
+				edges = append(edges, &Edge{From: id, To: existing, Back: true})
+				continue
+			}
+			ct.name = cmp.Or(ct.name, t.childKey(i))
+			childID := ids.child(id, i, ct.name, child)
+			visit(child, ct, childID)
+			edges = append(edges, &Edge{From: id, To: childID})
+		}
+	}
+
+	visit(root, readTreeNode(root, config.Fields), rootID)
+	return nodes, edges
+}
+
+// nodeIDs assigns unique, Mermaid-safe node IDs within a graph.
+type nodeIDs struct {
+	derive NodeIDFunc
+	used   map[string]bool
+	states map[string]string // State name -> ID, in state graphs
+}
+
+func newNodeIDs(config *DiagramConfig) *nodeIDs {
+	derive := config.NodeID
+	if derive == nil {
+		derive = PositionalNodeIDs
+	}
+	return &nodeIDs{derive: derive, used: make(map[string]bool)}
+}
+
+// newStateIDs returns an allocator for the state IDs of a state graph.
+func newStateIDs() *nodeIDs {
+	return &nodeIDs{used: make(map[string]bool), states: make(map[string]string)}
+}
+
+// unique escapes id and suffixes it with "_2", "_3", ... if it is already taken.
+func (a *nodeIDs) unique(id string) string {
+	id = mermaidID(id)
+	candidate := id
+	for n := 2; a.used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s_%d", id, n)
+	}
+	a.used[candidate] = true
+	return candidate
+}
+
+// subgraph returns a unique ID for a subgraph, also reserving the "_graph" ID
+// the Mermaid renderer gives it, so no node can take that ID later.
+func (a *nodeIDs) subgraph(id string) string {
+	id = mermaidID(id)
+	candidate := id
+	for n := 2; a.used[candidate] || a.used[candidate+"_graph"]; n++ {
+		candidate = fmt.Sprintf("%s_%d", id, n)
+	}
+	a.used[candidate] = true
+	a.used[candidate+"_graph"] = true
+	return candidate
+}
+
+// state returns the ID of the state called name, the same for every reference to it.
+// Pseudo-states keep their IDs, which renderers draw as [*].
+func (a *nodeIDs) state(name string) string {
+	if isPseudoState(name) {
+		return name
+	}
+	if id, ok := a.states[name]; ok {
+		return id
+	}
+	id := a.unique(name)
+	a.states[name] = id
+	return id
+}
+
+// child returns the ID of the index-th child of parentID.
+func (a *nodeIDs) child(parentID string, index int, name string, node any) string {
+	return a.unique(a.derive(parentID, index, name, node))
+}
+
+// treeRef identifies a pointer, map or slice by address and type.
+type treeRef struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// refOf returns the identity of a non-nil pointer or map or a non-empty slice,
+// or the zero treeRef.
+func refOf(v reflect.Value) treeRef {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map:
+		if v.IsNil() {
+			return treeRef{}
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return treeRef{}
+		}
+	default:
+		return treeRef{}
+	}
+	return treeRef{ptr: v.Pointer(), typ: v.Type()}
+}
+
+func (r treeRef) valid() bool {
+	return r.typ != nil
+}
+
+// treeKey identifies a node, or in a tree diff the pair of its two versions.
+// The zero treeKey identifies nothing.
+type treeKey [2]treeRef
+
+// treeVisits detects children that were already visited while walking a tree.
+// Nodes are identified by the pointer holding them and by the map or slice holding
+// their children; value nodes can only form cycles through the latter. Children maps
+// and slices are only tracked along the current path, so value nodes that happen to
+// share children are still visited separately.
+type treeVisits struct {
+	shared   bool               // Pointer nodes stay visited after leaving them (shared children)
+	pointers map[treeKey]string // Pointer nodes, by ID
+	children map[treeKey]string // Children of the nodes on the current path, by ID of their owner
+}
+
+func newTreeVisits(shared bool) *treeVisits {
+	return &treeVisits{shared: shared, pointers: make(map[treeKey]string), children: make(map[treeKey]string)}
+}
+
+// seen returns the ID of the visited node a child with the keys self and kids refers back to.
+func (v *treeVisits) seen(self, kids treeKey) (string, bool) {
+	if id, ok := v.pointers[self]; ok && self != (treeKey{}) {
+		return id, true
+	}
+	if id, ok := v.children[kids]; ok && kids != (treeKey{}) {
+		return id, true
+	}
+	return "", false
+}
+
+// enter records the node with the keys self and kids, drawn as id.
+func (v *treeVisits) enter(self, kids treeKey, id string) {
+	if self != (treeKey{}) {
+		v.pointers[self] = id
+	}
+	if kids != (treeKey{}) {
+		v.children[kids] = id
+	}
+}
+
+// leave forgets the children of a node once its subtree is done, and the node itself
+// unless shared children are tracked.
+func (v *treeVisits) leave(self, kids treeKey) {
+	if !v.shared {
+		delete(v.pointers, self)
+	}
+	delete(v.children, kids)
+}
+
+// treeFields holds the fields of a tree node extracted via reflection.
+type treeFields struct {
+	name     string
+	status   string
+	pid      int
+	metadata map[string]string
+	children []any
+	keys     []string // Map keys of children, if they come from a map
+
+	self treeRef // Pointer holding the node
+	kids treeRef // Map or slice holding the children
+}
+
+// visitKeys returns the keys identifying the node in treeVisits.
+func (t treeFields) visitKeys() (self, kids treeKey) {
+	if t.self.valid() {
+		self = treeKey{t.self}
+	}
+	if t.kids.valid() {
+		kids = treeKey{t.kids}
+	}
+	return self, kids
+}
+
+// childKey returns the map key of child i, or "".
+func (t treeFields) childKey(i int) string {
+	if i < len(t.keys) {
+		return t.keys[i]
+	}
+	return ""
+}
+
+// readTreeNode extracts the common tree fields of node. Nodes implementing TreeNode are read
+// through its methods; others via reflection on Name, Status, PID, Metadata and Children,
+// as mapped by fields. Children may be a slice, an array or a map (walked in sorted key order),
+// and fields may be promoted from embedded structs or held by pointers. A nil node,
+// including a TreeNode holding a nil pointer, is read as an empty node.
+func readTreeNode(node any, fields FieldMapping) treeFields {
+	if node == nil || isNilableAndNil(reflect.ValueOf(node)) {
+		return treeFields{}
+	}
+	if tn, ok := node.(TreeNode); ok {
+		children := tn.NodeChildren()
+		t := treeFields{
+			name:     tn.NodeName(),
+			status:   tn.NodeStatus(),
+			metadata: tn.NodeMetadata(),
+			children: make([]any, len(children)),
+		}
+		for i, child := range children {
+			t.children[i] = child
+		}
+		t.self = refOf(reflect.ValueOf(node))
+		return t
+	}
+
+	v := indirect(reflect.ValueOf(node))
+	if v.Kind() != reflect.Struct {
+		return treeFields{}
+	}
+	childrenField := mappedField(v, fieldChildren, fields.Children)
+	children, keys := childrenValue(childrenField)
+	return treeFields{
+		name:     stringValue(mappedField(v, fieldName, fields.Name)),
+		status:   stringValue(mappedField(v, fieldStatus, fields.Status)),
+		pid:      intValue(mappedField(v, fieldPID, fields.PID)),
+		metadata: mapValue(mappedField(v, fieldMetadata, fields.Metadata)),
+		children: children,
+		keys:     keys,
+		self:     refOf(reflect.ValueOf(node)),
+		kids:     refOf(childrenField),
+	}
+}
+
+// newTreeNode builds the diagram node of a tree element.
+func newTreeNode(id string, t treeFields, data any, config *DiagramConfig) *Node {
+	styler, labeler := config.NodeStyler, config.NodeLabeler
+	if styler == nil {
+		styler = defaultNodeStyler
+	}
+	if labeler == nil {
+		labeler = defaultNodeLabeler
+	}
+
+	icon, shapeStart, shapeEnd, idClass := styler(t.metadata)
+
+	statusClass := strings.ToLower(t.status)
+	if statusClass == "" {
+		statusClass = "pending"
+	}
+
+	return &Node{
+		ID:         id,
+		Label:      labeler(t.name, t.status, t.pid, t.metadata, icon),
+		ShapeStart: shapeStart,
+		ShapeEnd:   shapeEnd,
+		Classes:    []string{idClass, statusClass},
+		Name:       t.name,
+		Status:     t.status,
+		PID:        t.pid,
+		Metadata:   t.metadata,
+		Data:       data,
+	}
+}
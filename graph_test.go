@@ -0,0 +1,573 @@
+package introspection
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type graphTestNode struct {
+	Name     string
+	Status   string
+	PID      int
+	Metadata map[string]string
+	Children []graphTestNode
+}
+
+func TestBuildTreeGraph_Structure(t *testing.T) {
+	root := graphTestNode{
+		Name:     "root",
+		Status:   "Running",
+		Metadata: map[string]string{"type": "supervisor"},
+		Children: []graphTestNode{
+			{Name: "a", Status: "Failed", PID: 10},
+			{Name: "b", Children: []graphTestNode{{Name: "c", Status: "Stopped"}}},
+		},
+	}
+
+	config := DefaultDiagramConfig()
+	config.SecondaryID = "r"
+
+	g := BuildTreeGraph(root, config)
+
+	if g.Kind != FlowchartGraph {
+		t.Errorf("Kind = %v, want FlowchartGraph", g.Kind)
+	}
+
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, n.ID)
+	}
+	if want := []string{"r", "r_0", "r_1", "r_1_0"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("node IDs = %v, want %v", ids, want)
+	}
+
+	var edges [][2]string
+	for _, e := range g.Edges {
+		edges = append(edges, [2]string{e.From, e.To})
+	}
+	if want := [][2]string{{"r", "r_0"}, {"r_1", "r_1_0"}, {"r", "r_1"}}; !reflect.DeepEqual(edges, want) {
+		t.Errorf("edges = %v, want %v", edges, want)
+	}
+
+	tests := []struct {
+		id      string
+		classes []string
+		shape   string
+		pid     int
+	}{
+		{"r", []string{"supervisor", "running"}, "{{}}", 0},
+		{"r_0", []string{"process", "failed"}, "[]", 10},
+		{"r_1", []string{"process", "pending"}, "[]", 0},
+		{"r_1_0", []string{"process", "stopped"}, "[]", 0},
+	}
+	for _, tt := range tests {
+		n := g.Node(tt.id)
+		if n == nil {
+			t.Fatalf("Node(%q) = nil", tt.id)
+		}
+		if !reflect.DeepEqual(n.Classes, tt.classes) {
+			t.Errorf("Node(%q).Classes = %v, want %v", tt.id, n.Classes, tt.classes)
+		}
+		if shape := n.ShapeStart + n.ShapeEnd; shape != tt.shape {
+			t.Errorf("Node(%q) shape = %q, want %q", tt.id, shape, tt.shape)
+		}
+		if n.PID != tt.pid {
+			t.Errorf("Node(%q).PID = %d, want %d", tt.id, n.PID, tt.pid)
+		}
+	}
+
+	if _, ok := g.Node("r_0").Data.(graphTestNode); !ok {
+		t.Errorf("Node.Data = %T, want graphTestNode", g.Node("r_0").Data)
+	}
+}
+
+func TestBuildComponentGraph_Subgraphs(t *testing.T) {
+	config := DefaultDiagramConfig()
+	config.PrimaryID = "ctrl"
+	config.SecondaryID = "proc"
+	config.ConnectionLabel = "drives"
+
+	g := BuildComponentGraph(struct{ Enabled bool }{true}, graphTestNode{Name: "p", Children: []graphTestNode{{Name: "q"}}}, config)
+
+	if len(g.Subgraphs) != 2 {
+		t.Fatalf("len(Subgraphs) = %d, want 2", len(g.Subgraphs))
+	}
+	if sg := g.Subgraphs[0]; sg.ID != "ctrl" || len(sg.Nodes) != 1 || sg.Nodes[0].ID != "ctrl" {
+		t.Errorf("primary subgraph = %+v", sg)
+	}
+	if sg := g.Subgraphs[1]; sg.ID != "proc" || len(sg.Nodes) != 2 || len(sg.Edges) != 1 {
+		t.Errorf("secondary subgraph = %+v", sg)
+	}
+	if len(g.Edges) != 1 || *g.Edges[0] != (Edge{From: "ctrl", To: "proc", Label: "drives"}) {
+		t.Errorf("connection edges = %v", g.Edges)
+	}
+	if classes := g.Node("ctrl").Classes; !reflect.DeepEqual(classes, []string{"signal", "running"}) {
+		t.Errorf("primary classes = %v", classes)
+	}
+	if got := len(g.AllNodes()); got != 3 {
+		t.Errorf("len(AllNodes()) = %d, want 3", got)
+	}
+	if got := len(g.AllEdges()); got != 2 {
+		t.Errorf("len(AllEdges()) = %d, want 2", got)
+	}
+}
+
+func TestBuildStateMachineGraph_Highlight(t *testing.T) {
+	type state struct {
+		ForceExitThreshold int
+		Stopping           bool
+		Stopped            bool
+	}
+
+	tests := []struct {
+		name      string
+		state     state
+		highlight string
+		class     string
+		edges     int
+	}{
+		{"running", state{}, "Running", "running", 3},
+		{"stopping", state{Stopping: true, ForceExitThreshold: 2}, "Graceful", "stopping", 5},
+		{"stopped", state{Stopped: true}, EndStateID, "stopped", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := BuildStateMachineGraph(tt.state, nil)
+			if g.Kind != StateGraph {
+				t.Errorf("Kind = %v, want StateGraph", g.Kind)
+			}
+			if len(g.Edges) != tt.edges {
+				t.Errorf("len(Edges) = %d, want %d", len(g.Edges), tt.edges)
+			}
+			for _, n := range g.Nodes {
+				highlighted := len(n.Classes) > 0
+				if highlighted != (n.ID == tt.highlight) {
+					t.Errorf("node %q classes = %v", n.ID, n.Classes)
+				}
+			}
+			if n := g.Node(tt.highlight); n == nil || n.Classes[0] != tt.class {
+				t.Errorf("highlighted node %q = %+v, want class %q", tt.highlight, n, tt.class)
+			}
+		})
+	}
+}
+
+func TestGraph_RemoveNodes(t *testing.T) {
+	root := graphTestNode{
+		Name: "root",
+		Children: []graphTestNode{
+			{Name: "keep"},
+			{Name: "drop"},
+		},
+	}
+	g := BuildTreeGraph(root, nil)
+	g.Notes = []*Note{{Target: "secondary_1", Position: "right of", Text: "gone"}}
+
+	g.RemoveNodes(func(n *Node) bool { return n.Name == "drop" })
+
+	if g.Node("secondary_1") != nil {
+		t.Error("removed node still present")
+	}
+	if len(g.Edges) != 1 || g.Edges[0].To != "secondary_0" {
+		t.Errorf("edges after removal = %v", g.Edges)
+	}
+	if len(g.Notes) != 0 {
+		t.Errorf("notes after removal = %v", g.Notes)
+	}
+}
+
+func TestRenderer_PostProcessedGraph(t *testing.T) {
+	g := BuildTreeGraph(graphTestNode{Name: "root", Children: []graphTestNode{{Name: "child"}}}, nil, WithStyles(""))
+	for _, n := range g.AllNodes() {
+		n.Classes = append(n.Classes, "active")
+	}
+
+	renderers := map[string]Renderer{
+		"mermaid": MermaidRenderer{},
+		"dot":     DOTRenderer{},
+	}
+	for name, r := range renderers {
+		out := r.Render(g)
+		if out == "" {
+			t.Errorf("%s: empty output", name)
+		}
+		if name == "mermaid" && !strings.Contains(out, "class secondary_0 active") {
+			t.Errorf("%s: annotation not rendered\n%s", name, out)
+		}
+	}
+}
+
+func TestBuildTreeGraph_FieldMapping(t *testing.T) {
+	type tagged struct {
+		ID       string            `introspect:"name"`
+		Phase    string            `introspect:"status"`
+		Labels   map[string]string `introspect:"metadata"`
+		SubTasks []tagged          `introspect:"children"`
+		Name     string            // ignored: the tag wins over the default name
+	}
+	root := tagged{
+		ID:       "job",
+		Phase:    "Running",
+		Labels:   map[string]string{"type": "supervisor"},
+		SubTasks: []tagged{{ID: "step", Phase: "Failed", Name: "ignored"}},
+	}
+
+	g := BuildTreeGraph(root, nil)
+	if len(g.Nodes) != 2 {
+		t.Fatalf("got %d nodes, want 2", len(g.Nodes))
+	}
+	if n := g.Nodes[0]; n.Name != "job" || n.Status != "Running" || n.Classes[0] != "supervisor" {
+		t.Errorf("root = %+v", n)
+	}
+	if n := g.Nodes[1]; n.Name != "step" || !reflect.DeepEqual(n.Classes, []string{"process", "failed"}) {
+		t.Errorf("child = %+v", n)
+	}
+
+	// An explicit mapping wins over tags and default names.
+	type plain struct {
+		Key   string
+		State string
+		Kids  []plain
+	}
+	config := DefaultDiagramConfig()
+	config.Fields = FieldMapping{Name: "Key", Status: "State", Children: "Kids"}
+	g = BuildTreeGraph(plain{Key: "a", State: "Stopped", Kids: []plain{{Key: "b"}}}, config)
+	if len(g.Nodes) != 2 || g.Nodes[0].Name != "a" || g.Nodes[0].Status != "Stopped" || g.Nodes[1].Name != "b" {
+		t.Errorf("explicit mapping nodes = %+v %+v", g.Nodes[0], g.Nodes[len(g.Nodes)-1])
+	}
+
+	// The shallowest tag wins; equally deep duplicates are ambiguous and ignored.
+	type base struct {
+		Label string `introspect:"name"`
+		Phase string `introspect:"status"`
+	}
+	type other struct {
+		Phase string `introspect:"status"`
+	}
+	type embedding struct {
+		base
+		other
+		Key  string `introspect:"name"`
+		Name string
+	}
+	g = BuildTreeGraph(embedding{base{"deep", "Failed"}, other{"Stopped"}, "shallow", "default"}, nil)
+	if n := g.Nodes[0]; n.Name != "shallow" || n.Status != "" {
+		t.Errorf("embedded tags = %+v", n)
+	}
+}
+
+func TestBuildComponentGraph_PrimaryFieldMapping(t *testing.T) {
+	type controller struct {
+		Active  bool `introspect:"enabled"`
+		Halting bool
+		Why     string
+	}
+	config := DefaultDiagramConfig()
+	config.Fields = FieldMapping{Stopping: "Halting", Reason: "Why"}
+
+	g := BuildComponentGraph(controller{Active: true, Halting: true, Why: "drain"}, graphTestNode{Name: "w"}, config)
+	primary := g.Node(config.PrimaryID)
+	if primary == nil || primary.Classes[1] != "pending" || !strings.Contains(primary.Label, "Mode: Stopping<br/>Reason: drain") {
+		t.Errorf("primary = %+v", primary)
+	}
+}
+
+// lazyNode is a TreeNode whose children are materialized on demand behind a lock.
+type lazyNode struct {
+	mu       sync.Mutex
+	name     string
+	status   string
+	children []*lazyNode
+	calls    int
+}
+
+func (n *lazyNode) NodeName() string                { return n.name }
+func (n *lazyNode) NodeStatus() string              { return n.status }
+func (n *lazyNode) NodeMetadata() map[string]string { return map[string]string{"type": "container"} }
+
+func (n *lazyNode) NodeChildren() []TreeNode {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	nodes := make([]TreeNode, len(n.children))
+	for i, c := range n.children {
+		nodes[i] = c
+	}
+	return nodes
+}
+
+func TestBuildTreeGraph_TreeNode(t *testing.T) {
+	root := &lazyNode{name: "pod", status: "Running", children: []*lazyNode{
+		{name: "app", status: "Failed"},
+		{name: "sidecar", status: "Running"},
+	}}
+
+	g := BuildTreeGraph(root, nil)
+
+	var names []string
+	for _, n := range g.Nodes {
+		names = append(names, n.Name+":"+n.Status)
+	}
+	if want := []string{"pod:Running", "app:Failed", "sidecar:Running"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nodes = %v, want %v", names, want)
+	}
+	if n := g.Nodes[1]; n.ShapeStart != "[[" || n.Classes[1] != "failed" || n.Data != root.children[0] {
+		t.Errorf("child node = %+v", n)
+	}
+	if root.calls != 1 {
+		t.Errorf("NodeChildren called %d times, want 1", root.calls)
+	}
+
+	diagram := ComponentDiagram(struct{ Enabled bool }{true}, root, nil)
+	if !strings.Contains(diagram, "app") || !strings.Contains(diagram, "sidecar") {
+		t.Errorf("ComponentDiagram should render TreeNode children:\n%s", diagram)
+	}
+}
+
+func TestBuildTreeGraph_NilTreeNodeChild(t *testing.T) {
+	root := &lazyNode{name: "pod", children: []*lazyNode{nil, {name: "app"}}}
+
+	g := BuildTreeGraph(root, nil)
+	if len(g.Nodes) != 3 || g.Nodes[1].Name != "" || g.Nodes[2].Name != "app" {
+		t.Errorf("nodes = %+v", g.Nodes)
+	}
+	if report := NewHealthAggregator().Evaluate(root); len(report.Children) != 2 {
+		t.Errorf("health children = %+v", report.Children)
+	}
+}
+
+func TestBuildTreeGraph_MapPointerAndEmbedded(t *testing.T) {
+	type base struct {
+		Name   string
+		Status string
+	}
+	type node struct {
+		*base
+		Children map[string]*node
+	}
+	root := &node{base: &base{Name: "root", Status: "Running"}, Children: map[string]*node{
+		"zeta":  {base: &base{Name: "z"}},
+		"alpha": {Children: map[string]*node{"leaf": {base: &base{Status: "Failed"}}}}, // nil embedded base
+	}}
+
+	g := BuildTreeGraph(root, nil)
+
+	var names []string
+	for _, n := range g.Nodes {
+		names = append(names, n.Name+":"+n.Status)
+	}
+	// Map children are sorted by key and unnamed children are named after their key.
+	if want := []string{"root:Running", "alpha:", "leaf:Failed", "z:"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nodes = %v, want %v", names, want)
+	}
+}
+
+func TestBuildTreeGraph_Cycle(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+	}
+	shared := &node{Name: "shared"}
+	root := &node{Name: "root"}
+	a := &node{Name: "a", Children: []*node{shared, root}} // root closes a cycle
+	root.Children = []*node{a, shared}
+
+	config := DefaultDiagramConfig()
+	config.SecondaryID = "r"
+	g := BuildTreeGraph(root, config)
+
+	if len(g.Nodes) != 3 {
+		t.Fatalf("got %d nodes, want 3 (each drawn once)", len(g.Nodes))
+	}
+	var back []string
+	for _, e := range g.Edges {
+		if e.Back {
+			back = append(back, e.From+"->"+e.To)
+		}
+	}
+	if want := []string{"r_0->r", "r->r_0_0"}; !reflect.DeepEqual(back, want) {
+		t.Errorf("back edges = %v, want %v", back, want)
+	}
+
+	diagram := MermaidRenderer{}.Render(g)
+	if !strings.Contains(diagram, "r_0 -.-> r\n") {
+		t.Errorf("back edge should be dashed:\n%s", diagram)
+	}
+	if dot := (DOTRenderer{}).Render(g); !strings.Contains(dot, "r_0 -> r [style=dashed];") {
+		t.Errorf("DOT back edge should be dashed:\n%s", dot)
+	}
+
+	report := NewHealthAggregator().Evaluate(root)
+	if len(report.Children) != 2 || len(report.Children[0].Children) != 1 {
+		t.Errorf("health should skip the cycle: %+v", report)
+	}
+
+	diff := BuildTreeDiffGraph(root, root, config)
+	if len(diff.Nodes) != 3 {
+		t.Errorf("tree diff should draw each node once, got %d", len(diff.Nodes))
+	}
+}
+
+func TestBuildTreeGraph_NodeIDs(t *testing.T) {
+	root := graphTestNode{Name: "root", Children: []graphTestNode{
+		{Name: "api-gateway"},
+		{Name: "worker", Children: []graphTestNode{{Name: "worker"}, {}}},
+		{Name: "end"},
+	}}
+	ids := func(nodes []*Node) []string {
+		var ids []string
+		for _, n := range nodes {
+			ids = append(ids, n.ID)
+		}
+		return ids
+	}
+
+	config := DefaultDiagramConfig()
+	config.NodeID = NameNodeIDs
+	want := []string{"secondary", "api_gateway", "worker", "worker_2", "worker_1", "end_"}
+	if got := ids(BuildTreeGraph(root, config).Nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("name IDs = %v, want %v", got, want)
+	}
+
+	// Inserting a sibling leaves the other IDs unchanged.
+	root.Children = append([]graphTestNode{{Name: "db"}}, root.Children...)
+	want = append([]string{"secondary", "db"}, want[1:]...)
+	if got := ids(BuildTreeGraph(root, config).Nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("name IDs after insert = %v, want %v", got, want)
+	}
+
+	config.NodeID = func(parentID string, index int, name string, node any) string {
+		return "task:" + name
+	}
+	if got := ids(BuildTreeGraph(graphTestNode{Name: "a", Children: []graphTestNode{{Name: "b"}, {Name: "b"}}}, config).Nodes); !reflect.DeepEqual(got, []string{"secondary", "task_b", "task_b_2"}) {
+		t.Errorf("custom IDs = %v", got)
+	}
+
+	// Subgraph IDs are reserved, so no node can take the ID Mermaid gives a subgraph.
+	config.NodeID = NameNodeIDs
+	g := BuildComponentGraph(struct{}{}, graphTestNode{Name: "root", Children: []graphTestNode{{Name: "secondary_graph"}, {Name: "primary"}}}, config)
+	if got := ids(g.Subgraphs[1].Nodes); !reflect.DeepEqual(got, []string{"secondary", "secondary_graph_2", "primary_2"}) {
+		t.Errorf("component IDs = %v", got)
+	}
+}
+
+func TestBuildStateGraphs_UniqueStateIDs(t *testing.T) {
+	m := &StateMachine{
+		States:      []State{{Name: "a-b", Initial: true}, {Name: "a_b", Final: true}},
+		Transitions: []Transition{{From: "a-b", To: "a_b", Event: "go"}},
+	}
+	g := m.Build("a_b")
+	if len(g.Nodes) != 4 || g.Nodes[1].ID != "a_b" || g.Nodes[2].ID != "a_b_2" {
+		t.Fatalf("nodes = %+v", g.Nodes)
+	}
+	if e := g.Edges[len(g.Edges)-1]; e.From != "a_b" || e.To != "a_b_2" {
+		t.Errorf("transition = %+v", e)
+	}
+	out := MermaidRenderer{}.Render(g)
+	for _, want := range []string{`state "a-b" as a_b`, `state "a_b" as a_b_2`, "a_b --> a_b_2: go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diagram missing %q:\n%s", want, out)
+		}
+	}
+
+	config := DefaultStateMachineConfig()
+	config.InitialState, config.GracefulState = "Shutting-down", "Shutting down"
+	g = BuildStateMachineGraph(struct{}{}, config)
+	if g.Nodes[1].ID != "Shutting_down" || g.Nodes[2].ID != "Shutting_down_2" {
+		t.Errorf("nodes = %+v %+v", g.Nodes[1], g.Nodes[2])
+	}
+}
+
+func TestMermaidRenderer_EscapesIDs(t *testing.T) {
+	g := &Graph{
+		Kind:      FlowchartGraph,
+		Nodes:     []*Node{{ID: "my node", Label: "x", ShapeStart: "[", ShapeEnd: "]"}, {ID: "end", Label: "y", ShapeStart: "[", ShapeEnd: "]"}},
+		Edges:     []*Edge{{From: "my node", To: "end"}},
+		Subgraphs: []*Subgraph{{ID: "sub-graph", Label: "S"}},
+	}
+	out := MermaidRenderer{}.Render(g)
+	for _, want := range []string{"subgraph sub_graph_graph [S]", `my_node["x"]`, `end_["y"]`, "my_node --> end_"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	states := &Graph{
+		Kind:  StateGraph,
+		Nodes: []*Node{{ID: "Force Exit"}, {ID: "Running"}},
+		Edges: []*Edge{{From: "Running", To: "Force Exit"}},
+	}
+	out = MermaidRenderer{}.Render(states)
+	for _, want := range []string{`state "Force Exit" as Force_Exit`, "Running --> Force_Exit"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// mapCycleNode and sliceCycleNode are value nodes whose children container holds
+// a copy of the node itself, so they cycle without any pointer.
+type mapCycleNode struct {
+	Name     string
+	Status   string
+	Children map[string]mapCycleNode
+}
+
+type sliceCycleNode struct {
+	Name     string
+	Status   string
+	Children []sliceCycleNode
+}
+
+func newValueCycles() (mapCycleNode, sliceCycleNode) {
+	m := mapCycleNode{Name: "root", Children: map[string]mapCycleNode{}}
+	m.Children["leaf"] = mapCycleNode{Name: "leaf", Status: "Failed"}
+	m.Children["self"] = m
+
+	s := sliceCycleNode{Name: "root", Children: make([]sliceCycleNode, 2)}
+	s.Children[0] = sliceCycleNode{Name: "leaf", Status: "Failed"}
+	s.Children[1] = s
+	return m, s
+}
+
+func TestBuildTreeGraph_ValueCycles(t *testing.T) {
+	m, s := newValueCycles()
+	config := DefaultDiagramConfig()
+	config.SecondaryID = "r"
+
+	for name, root := range map[string]any{"map": m, "slice": s} {
+		g := BuildTreeGraph(root, config)
+
+		var names []string
+		for _, n := range g.Nodes {
+			names = append(names, n.Name)
+		}
+		if want := []string{"root", "leaf"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: nodes = %v, want %v", name, names, want)
+		}
+		var back []string
+		for _, e := range g.Edges {
+			if e.Back {
+				back = append(back, e.From+"->"+e.To)
+			}
+		}
+		if want := []string{"r->r"}; !reflect.DeepEqual(back, want) {
+			t.Errorf("%s: back edges = %v, want %v", name, back, want)
+		}
+		if diagram := TreeDiagram(root, config); !strings.Contains(diagram, "r -.-> r\n") {
+			t.Errorf("%s: missing dashed back edge:\n%s", name, diagram)
+		}
+		if diff := BuildTreeDiffGraph(root, root, config); len(diff.Nodes) != 2 {
+			t.Errorf("%s: tree diff drew %d nodes, want 2", name, len(diff.Nodes))
+		}
+	}
+
+	// Value nodes that merely share a children slice are still drawn separately.
+	shared := []sliceCycleNode{{Name: "leaf"}}
+	root := sliceCycleNode{Name: "root", Children: []sliceCycleNode{{Name: "a", Children: shared}, {Name: "b", Children: shared}}}
+	if g := BuildTreeGraph(root, config); len(g.Nodes) != 5 {
+		t.Errorf("shared children: got %d nodes, want 5", len(g.Nodes))
+	}
+}
@@ -0,0 +1,282 @@
+package introspection
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// HealthStatus is the health of a component.
+type HealthStatus string
+
+const (
+	HealthHealthy   HealthStatus = "healthy"
+	HealthUnknown   HealthStatus = "unknown"
+	HealthDegraded  HealthStatus = "degraded"
+	HealthUnhealthy HealthStatus = "unhealthy"
+)
+
+// severity orders statuses from best to worst.
+func (s HealthStatus) severity() int {
+	switch s {
+	case HealthHealthy:
+		return 0
+	case HealthDegraded:
+		return 2
+	case HealthUnhealthy:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// worseHealth returns the more severe of a and b.
+func worseHealth(a, b HealthStatus) HealthStatus {
+	if b.severity() > a.severity() {
+		return b
+	}
+	return a
+}
+
+// HealthCheck is the result of a single named check.
+type HealthCheck struct {
+	Name   string       `json:"name"`
+	Status HealthStatus `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// Health is the self-reported health of a component.
+type Health struct {
+	Status HealthStatus  // If empty, the worst check status (healthy without checks)
+	Reason string        // Human-readable explanation of a non-healthy status
+	Checks []HealthCheck // Optional individual checks
+}
+
+// HealthReporter is an optional interface for components that report their own health.
+// The HealthAggregator uses it instead of deriving health from the component's status.
+type HealthReporter interface {
+	Health() Health
+}
+
+// HealthReport is the evaluated health of a component and its children.
+type HealthReport struct {
+	Name            string         `json:"name"`
+	Status          HealthStatus   `json:"status"`
+	Reason          string         `json:"reason,omitempty"`
+	ComponentStatus string         `json:"componentStatus,omitempty"` // The component's own Status field
+	Checks          []HealthCheck  `json:"checks,omitempty"`
+	Children        []HealthReport `json:"children,omitempty"`
+
+	Data any `json:"-"` // The state the report was evaluated from
+
+	fields FieldMapping // The mapping Data was read with, for rules that read its fields
+}
+
+// Live reports whether the component is alive, i.e. not unhealthy.
+func (r HealthReport) Live() bool {
+	return r.Status != HealthUnhealthy
+}
+
+// Ready reports whether the component is ready to serve, i.e. healthy.
+func (r HealthReport) Ready() bool {
+	return r.Status == HealthHealthy
+}
+
+// HealthRule adjusts the health of a node after its children are evaluated.
+// It returns the status the node should have at least, with a reason, or ""
+// if it does not apply. Rules can only make a node's status worse.
+type HealthRule func(node *HealthReport) (HealthStatus, string)
+
+// WhenAnyChild is a HealthRule that sets a node to then when any of its children
+// is at least as unhealthy as child, e.g. WhenAnyChild(HealthUnhealthy, HealthDegraded).
+func WhenAnyChild(child, then HealthStatus) HealthRule {
+	return func(node *HealthReport) (HealthStatus, string) {
+		for _, c := range node.Children {
+			if c.Status.severity() >= child.severity() {
+				return then, fmt.Sprintf("child %s is %s", c.Name, c.Status)
+			}
+		}
+		return "", ""
+	}
+}
+
+// WhenStatus is a HealthRule that sets a node to then when its component status
+// matches status (case-insensitively), e.g. WhenStatus("Stopping", HealthUnhealthy).
+func WhenStatus(status string, then HealthStatus) HealthRule {
+	return func(node *HealthReport) (HealthStatus, string) {
+		if strings.EqualFold(node.ComponentStatus, status) {
+			return then, "status is " + node.ComponentStatus
+		}
+		return "", ""
+	}
+}
+
+// WhenStopping is a HealthRule that sets a node to then when its Stopping field is
+// true, e.g. WhenStopping(HealthUnhealthy). The field is found as for the primary
+// component of ComponentDiagram: by WithHealthFields, an introspect tag or its name.
+func WhenStopping(then HealthStatus) HealthRule {
+	return func(node *HealthReport) (HealthStatus, string) {
+		if boolValue(mappedField(reflect.ValueOf(node.Data), fieldStopping, node.fields.Stopping)) {
+			return then, "stopping"
+		}
+		return "", ""
+	}
+}
+
+// WhenDisabled is a HealthRule that sets a node to then when it has an Enabled field
+// and it is false. The field is found as for WhenStopping.
+func WhenDisabled(then HealthStatus) HealthRule {
+	return func(node *HealthReport) (HealthStatus, string) {
+		enabled := mappedField(reflect.ValueOf(node.Data), fieldEnabled, node.fields.Enabled)
+		if enabled.IsValid() && enabled.Kind() == reflect.Bool && !enabled.Bool() {
+			return then, "disabled"
+		}
+		return "", ""
+	}
+}
+
+// DefaultHealthRules returns the rules used by NewHealthAggregator:
+// a node is degraded when any child is degraded or unhealthy.
+func DefaultHealthRules() []HealthRule {
+	return []HealthRule{WhenAnyChild(HealthDegraded, HealthDegraded)}
+}
+
+// DefaultStatusHealth maps a component status to a health status:
+// failed, killed and crashed are unhealthy; stopping, stopped and suspended are
+// degraded; anything else is healthy.
+func DefaultStatusHealth(status string) HealthStatus {
+	switch strings.ToLower(status) {
+	case "failed", "killed", "crashed":
+		return HealthUnhealthy
+	case "stopping", "stopped", "suspended":
+		return HealthDegraded
+	default:
+		return HealthHealthy
+	}
+}
+
+// HealthOption configures a HealthAggregator.
+type HealthOption func(*HealthAggregator)
+
+// WithHealthRules replaces the default rules (DefaultHealthRules).
+func WithHealthRules(rules ...HealthRule) HealthOption {
+	return func(a *HealthAggregator) {
+		a.rules = rules
+	}
+}
+
+// WithStatusHealth sets how components that do not implement HealthReporter
+// are judged from their status (default: DefaultStatusHealth).
+func WithStatusHealth(f func(status string) HealthStatus) HealthOption {
+	return func(a *HealthAggregator) {
+		a.statusHealth = f
+	}
+}
+
+// WithHealthFields sets the field mapping used to find names, statuses and children,
+// and the fields read by WhenStopping and WhenDisabled, as DiagramConfig.Fields does
+// for diagrams.
+func WithHealthFields(fields FieldMapping) HealthOption {
+	return func(a *HealthAggregator) {
+		a.fields = fields
+	}
+}
+
+// HealthAggregator rolls component health up through the same hierarchy TreeDiagram
+// draws from Children. Each node's own health comes from HealthReporter, or from its
+// Status field otherwise; rules then escalate it based on the node and its children:
+//
+//	health := NewHealthAggregator(WithHealthRules(
+//		WhenAnyChild(HealthUnhealthy, HealthDegraded),
+//		WhenStatus("Stopping", HealthUnhealthy),
+//	))
+//	report := health.Evaluate(supervisor.State())
+//	if !report.Ready() { ... }
+type HealthAggregator struct {
+	rules        []HealthRule
+	statusHealth func(string) HealthStatus
+	fields       FieldMapping
+}
+
+// NewHealthAggregator creates a HealthAggregator with the given options.
+func NewHealthAggregator(opts ...HealthOption) *HealthAggregator {
+	a := &HealthAggregator{
+		rules:        DefaultHealthRules(),
+		statusHealth: DefaultStatusHealth,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Evaluate returns the health of root and its descendants. A child that is one of
+// its own ancestors, through a cycle of pointers, maps or slices, is not evaluated again.
+func (a *HealthAggregator) Evaluate(root any) HealthReport {
+	return a.evaluate(root, readTreeNode(root, a.fields), newTreeVisits(false))
+}
+
+// evaluate returns the health of root, given the nodes on the path to it.
+func (a *HealthAggregator) evaluate(root any, t treeFields, visits *treeVisits) HealthReport {
+	self, kids := t.visitKeys()
+	visits.enter(self, kids, t.name)
+	defer visits.leave(self, kids)
+
+	report := HealthReport{
+		Name:            t.name,
+		ComponentStatus: t.status,
+		Data:            root,
+		fields:          a.fields,
+	}
+	if reporter, ok := root.(HealthReporter); ok {
+		h := reporter.Health()
+		report.Status, report.Reason, report.Checks = h.Status, h.Reason, h.Checks
+		if report.Status == "" {
+			report.Status = HealthHealthy
+			for _, c := range h.Checks {
+				report.Status = worseHealth(report.Status, c.Status)
+			}
+		}
+	} else {
+		report.Status = a.statusHealth(t.status)
+		if report.Status != HealthHealthy && t.status != "" {
+			report.Reason = "status is " + t.status
+		}
+	}
+
+	for i, child := range t.children {
+		ct := readTreeNode(child, a.fields)
+		if _, seen := visits.seen(ct.visitKeys()); seen {
+			continue
+		}
+		ct.name = cmp.Or(ct.name, t.childKey(i))
+		report.Children = append(report.Children, a.evaluate(child, ct, visits))
+	}
+	a.applyRules(&report)
+	return report
+}
+
+// EvaluateSnapshots returns the health of a set of components, such as the latest
+// snapshots of a StateStore. Each payload is evaluated as a tree and named after its
+// component; the root, named "system", has one child per snapshot.
+func (a *HealthAggregator) EvaluateSnapshots(snapshots []StateSnapshot) HealthReport {
+	report := HealthReport{Name: "system", Status: HealthHealthy}
+	for _, s := range snapshots {
+		child := a.Evaluate(s.Payload)
+		child.Name = s.ComponentType + "/" + s.ComponentID
+		report.Children = append(report.Children, child)
+	}
+	a.applyRules(&report)
+	return report
+}
+
+// applyRules escalates the status of node according to the rules.
+func (a *HealthAggregator) applyRules(node *HealthReport) {
+	for _, rule := range a.rules {
+		status, reason := rule(node)
+		if status != "" && status.severity() > node.Status.severity() {
+			node.Status, node.Reason = status, reason
+		}
+	}
+}
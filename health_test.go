@@ -0,0 +1,159 @@
+package introspection
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type healthNode struct {
+	Name     string
+	Status   string
+	Children []healthNode
+}
+
+type reportingNode struct {
+	Name  string
+	Cache HealthStatus
+}
+
+func (n reportingNode) Health() Health {
+	return Health{Checks: []HealthCheck{
+		{Name: "db", Status: HealthHealthy},
+		{Name: "cache", Status: n.Cache, Reason: "evictions"},
+	}}
+}
+
+func TestHealthAggregator_RollUp(t *testing.T) {
+	root := healthNode{
+		Name:   "root",
+		Status: "Running",
+		Children: []healthNode{
+			{Name: "a", Status: "Running"},
+			{Name: "b", Status: "Running", Children: []healthNode{{Name: "b1", Status: "Failed"}}},
+		},
+	}
+
+	report := NewHealthAggregator().Evaluate(root)
+
+	if report.Status != HealthDegraded || report.Reason != "child b is degraded" {
+		t.Errorf("root = %s (%s), want degraded", report.Status, report.Reason)
+	}
+	if b := report.Children[1]; b.Status != HealthDegraded || b.Reason != "child b1 is unhealthy" {
+		t.Errorf("b = %s (%s)", b.Status, b.Reason)
+	}
+	if b1 := report.Children[1].Children[0]; b1.Status != HealthUnhealthy || b1.Reason != "status is Failed" || b1.ComponentStatus != "Failed" {
+		t.Errorf("b1 = %+v", b1)
+	}
+	if !report.Live() || report.Ready() {
+		t.Errorf("Live() = %v, Ready() = %v; want live and not ready", report.Live(), report.Ready())
+	}
+}
+
+func TestHealthAggregator_Rules(t *testing.T) {
+	agg := NewHealthAggregator(WithHealthRules(
+		WhenAnyChild(HealthUnhealthy, HealthUnhealthy),
+		WhenStatus("stopping", HealthUnhealthy),
+	))
+
+	stopping := agg.Evaluate(healthNode{Name: "primary", Status: "Stopping"})
+	if stopping.Status != HealthUnhealthy || stopping.Reason != "status is Stopping" {
+		t.Errorf("stopping = %s (%s), want unhealthy", stopping.Status, stopping.Reason)
+	}
+
+	// Primary components report stopping and disabled through fields, not Status.
+	type primary struct {
+		Name    string
+		Enabled bool
+		Halting bool `introspect:"stopping"`
+	}
+	agg = NewHealthAggregator(WithHealthRules(WhenStopping(HealthUnhealthy), WhenDisabled(HealthDegraded)))
+	if r := agg.Evaluate(primary{Name: "p", Enabled: true, Halting: true}); r.Status != HealthUnhealthy || r.Reason != "stopping" {
+		t.Errorf("stopping primary = %s (%s), want unhealthy", r.Status, r.Reason)
+	}
+	if r := agg.Evaluate(primary{Name: "p"}); r.Status != HealthDegraded || r.Reason != "disabled" {
+		t.Errorf("disabled primary = %s (%s), want degraded", r.Status, r.Reason)
+	}
+	if r := agg.Evaluate(healthNode{Name: "n"}); r.Status != HealthHealthy {
+		t.Errorf("node without Enabled = %s (%s), want healthy", r.Status, r.Reason)
+	}
+
+	type renamed struct {
+		Name string
+		Busy bool
+	}
+	agg = NewHealthAggregator(
+		WithHealthRules(WhenStopping(HealthUnhealthy)),
+		WithHealthFields(FieldMapping{Stopping: "Busy"}),
+	)
+	if r := agg.Evaluate(&renamed{Name: "p", Busy: true}); r.Status != HealthUnhealthy {
+		t.Errorf("mapped stopping = %s (%s), want unhealthy", r.Status, r.Reason)
+	}
+
+	// Rules never improve a status.
+	agg = NewHealthAggregator(
+		WithHealthRules(func(*HealthReport) (HealthStatus, string) { return HealthHealthy, "fine" }),
+		WithStatusHealth(func(string) HealthStatus { return HealthUnknown }),
+	)
+	if r := agg.Evaluate(healthNode{Name: "x"}); r.Status != HealthUnknown || r.Live() == false {
+		t.Errorf("unknown = %+v", r)
+	}
+}
+
+func TestHealthAggregator_Reporter(t *testing.T) {
+	agg := NewHealthAggregator()
+
+	report := agg.Evaluate(reportingNode{Name: "svc", Cache: HealthDegraded})
+	if report.Status != HealthDegraded || len(report.Checks) != 2 || report.Name != "svc" {
+		t.Errorf("report = %+v", report)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"status":"degraded"`, `"name":"cache"`, `"reason":"evictions"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON missing %s: %s", want, data)
+		}
+	}
+}
+
+func TestHealthAggregator_Snapshots(t *testing.T) {
+	report := NewHealthAggregator().EvaluateSnapshots([]StateSnapshot{
+		{ComponentType: "worker", ComponentID: "w1", Payload: healthNode{Status: "Running"}},
+		{ComponentType: "worker", ComponentID: "w2", Payload: healthNode{Status: "Killed"}},
+	})
+	if report.Name != "system" || report.Status != HealthDegraded || len(report.Children) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if w2 := report.Children[1]; w2.Name != "worker/w2" || w2.Status != HealthUnhealthy {
+		t.Errorf("w2 = %+v", w2)
+	}
+}
+
+func TestHealthAggregator_Fields(t *testing.T) {
+	type task struct {
+		ID       string `introspect:"name"`
+		Phase    string
+		SubTasks []task `introspect:"children"`
+	}
+	agg := NewHealthAggregator(WithHealthFields(FieldMapping{Status: "Phase"}))
+	report := agg.Evaluate(task{ID: "job", Phase: "Running", SubTasks: []task{{ID: "step", Phase: "Failed"}}})
+	if report.Name != "job" || report.Status != HealthDegraded || report.Children[0].Name != "step" {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestHealthAggregator_ValueCycles(t *testing.T) {
+	m, s := newValueCycles()
+	for name, root := range map[string]any{"map": m, "slice": s} {
+		report := NewHealthAggregator().Evaluate(root)
+		if len(report.Children) != 1 || report.Children[0].Name != "leaf" {
+			t.Errorf("%s: children = %+v, want only leaf", name, report.Children)
+		}
+		if report.Status != HealthDegraded {
+			t.Errorf("%s: status = %s, want degraded", name, report.Status)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package httpx
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+// DefaultMermaidURL is the Mermaid ES module loaded by the dashboard page.
+const DefaultMermaidURL = "https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.esm.min.mjs"
+
+// DefaultDashboardTitle is the heading of the dashboard page.
+const DefaultDashboardTitle = "Introspection"
+
+// defaultMaxEvents is the number of entries kept in the dashboard event log.
+const defaultMaxEvents = 200
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+// dashboardData is the template input of the dashboard page.
+type dashboardData struct {
+	Title      string
+	MermaidURL string
+	Diagrams   []string
+	Selected   string
+	MaxEvents  int
+}
+
+// WithMermaidURL sets the URL the dashboard page loads the Mermaid ES module from.
+// Point it at a self-hosted copy for air-gapped environments.
+func WithMermaidURL(url string) Option {
+	return func(h *Handler) {
+		h.mermaidURL = url
+	}
+}
+
+// WithDashboardTitle sets the heading of the dashboard page.
+func WithDashboardTitle(title string) Option {
+	return func(h *Handler) {
+		h.title = title
+	}
+}
+
+// WithDashboardDiagram selects the diagram shown when the dashboard opens.
+// By default the first registered diagram is shown.
+func WithDashboardDiagram(name string) Option {
+	return func(h *Handler) {
+		h.selected = name
+	}
+}
+
+// handleDashboard serves the self-contained HTML dashboard.
+// The page renders the selected diagram with Mermaid, re-renders it whenever the
+// stream reports a snapshot, and shows component events in a log panel.
+func (h *Handler) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	selected := h.selected
+	if _, ok := h.diagrams[selected]; !ok && len(h.diagramNames) > 0 {
+		selected = h.diagramNames[0]
+	}
+
+	var buf bytes.Buffer
+	err := dashboardTemplate.Execute(&buf, dashboardData{
+		Title:      h.title,
+		MermaidURL: h.mermaidURL,
+		Diagrams:   h.diagramNames,
+		Selected:   selected,
+		MaxEvents:  defaultMaxEvents,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
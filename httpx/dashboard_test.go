@@ -0,0 +1,63 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Dashboard(t *testing.T) {
+	h := NewHandler(
+		WithDiagram("tree", func() string { return "graph TD\n" }),
+		WithDiagram("components", func() string { return "graph TD\n" }),
+		WithDashboardDiagram("components"),
+		WithDashboardTitle("Supervisor <debug>"),
+		WithMermaidURL("/static/mermaid.mjs"),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / status = %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+
+	body := rec.Body.String()
+	expected := []string{
+		`import mermaid from "/static/mermaid.mjs"`,
+		`<option value="tree">tree</option>`,
+		`<option value="components" selected>components</option>`,
+		`Supervisor &lt;debug&gt;`,
+		`new EventSource("stream")`,
+		`"diagrams/" + encodeURIComponent(name)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+	if strings.Contains(body, "cdn.jsdelivr.net") {
+		t.Error("dashboard should not reference the default CDN when WithMermaidURL is set")
+	}
+}
+
+func TestHandler_Dashboard_DefaultsToFirstDiagram(t *testing.T) {
+	h := NewHandler(
+		WithDiagram("first", func() string { return "" }),
+		WithDiagram("second", func() string { return "" }),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.Contains(rec.Body.String(), `<option value="first" selected>`) {
+		t.Error("dashboard should select the first registered diagram by default")
+	}
+	if !strings.Contains(rec.Body.String(), DefaultMermaidURL[len("https://"):]) {
+		t.Error("dashboard should load Mermaid from DefaultMermaidURL by default")
+	}
+}
@@ -0,0 +1,237 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aretw0/introspection"
+)
+
+// versionHeader carries the StateStore version a state response was read at.
+const versionHeader = "X-Introspection-Version"
+
+// snapshotJSON is the wire format of an introspection.StateSnapshot.
+type snapshotJSON struct {
+	ComponentType string    `json:"componentType"`
+	ComponentID   string    `json:"componentId"`
+	Timestamp     time.Time `json:"timestamp"`
+	Payload       any       `json:"payload"`
+}
+
+// eventJSON is the wire format of an introspection.ComponentEvent.
+type eventJSON struct {
+	ComponentType string    `json:"componentType"`
+	ComponentID   string    `json:"componentId"`
+	EventType     string    `json:"eventType"`
+	Timestamp     time.Time `json:"timestamp"`
+	Payload       any       `json:"payload,omitempty"`
+}
+
+func newSnapshotJSON(s introspection.StateSnapshot) snapshotJSON {
+	return snapshotJSON{
+		ComponentType: s.ComponentType,
+		ComponentID:   s.ComponentID,
+		Timestamp:     s.Timestamp,
+		Payload:       s.Payload,
+	}
+}
+
+func newEventJSON(e introspection.ComponentEvent) eventJSON {
+	return eventJSON{
+		ComponentType: e.ComponentType(),
+		ComponentID:   e.ComponentID(),
+		EventType:     e.EventType(),
+		Timestamp:     e.Timestamp(),
+		Payload:       e,
+	}
+}
+
+// handleStateList serves every component's latest snapshot.
+// With ?since=N it blocks until the store version is greater than N.
+func (h *Handler) handleStateList(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		version, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		if _, err := h.store.WaitForChange(r.Context(), version); err != nil {
+			// Client went away or timed out; nothing useful to send.
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	version := h.store.Version()
+	list := h.store.List()
+
+	result := make([]snapshotJSON, len(list))
+	for i, s := range list {
+		result[i] = newSnapshotJSON(s)
+	}
+
+	w.Header().Set(versionHeader, strconv.FormatUint(version, 10))
+	writeJSON(w, result)
+}
+
+// handleState serves a single component's latest snapshot.
+func (h *Handler) handleState(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	snapshot, ok := h.store.Get(r.PathValue("type"), r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set(versionHeader, strconv.FormatUint(h.store.Version(), 10))
+	writeJSON(w, newSnapshotJSON(snapshot))
+}
+
+// handleDiagramList serves the names of the registered diagrams.
+func (h *Handler) handleDiagramList(w http.ResponseWriter, r *http.Request) {
+	names := h.diagramNames
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, names)
+}
+
+// handleDiagram renders a registered diagram.
+func (h *Handler) handleDiagram(w http.ResponseWriter, r *http.Request) {
+	render, ok := h.diagrams[r.PathValue("name")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte(render()))
+}
+
+// handleHealth serves the health report, with 503 Service Unavailable when ok rejects it.
+func (h *Handler) handleHealth(ok func(introspection.HealthReport) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.health == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		report := h.health()
+		w.Header().Set("Cache-Control", "no-store")
+		status := http.StatusOK
+		if !ok(report) {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSONStatus(w, status, report)
+	}
+}
+
+// handleStream serves snapshots and events as Server-Sent Events.
+// Snapshots use the "snapshot" event name and component events use "event".
+func (h *Handler) handleStream(w http.ResponseWriter, r *http.Request) {
+	if h.snapshots == nil && len(h.events) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+
+	var snapshots <-chan introspection.StateSnapshot
+	if h.snapshots != nil {
+		snapshots = h.snapshots.Snapshots(ctx)
+	}
+	var events <-chan introspection.ComponentEvent
+	switch len(h.events) {
+	case 0:
+	case 1:
+		events = h.events[0].Events(ctx)
+	default:
+		events = introspection.AggregateEvents(ctx, h.events...)
+	}
+
+	var heartbeat <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for snapshots != nil || events != nil {
+		var err error
+		select {
+		case s, ok := <-snapshots:
+			if !ok {
+				snapshots = nil
+				continue
+			}
+			err = writeEvent(w, "snapshot", newSnapshotJSON(s))
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			err = writeEvent(w, "event", newEventJSON(e))
+		case <-heartbeat:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a single Server-Sent Event with a JSON payload.
+func writeEvent(w http.ResponseWriter, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
+
+// writeJSON writes v as an indented JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	writeJSONStatus(w, http.StatusOK, v)
+}
+
+// writeJSONStatus writes v as indented JSON with the given status code. The value is
+// marshaled before anything is written, so a marshaling error can still become a 500.
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,191 @@
+// Package httpx exposes introspection state, diagrams and live streams over net/http.
+//
+// The Handler is built on the standard library only and is meant to be mounted
+// as a debug endpoint, much like net/http/pprof:
+//
+//	store := introspection.NewStateStore()
+//	go store.Consume(ctx, registry.Snapshots(ctx))
+//
+//	h := httpx.NewHandler(
+//		httpx.WithStore(store),
+//		httpx.WithSnapshots(registry),
+//		httpx.WithEvents(events),
+//		httpx.WithDiagram("tree", httpx.Tree(supervisor, nil)),
+//		httpx.WithHealth(httpx.StoreHealth(introspection.NewHealthAggregator(), store)),
+//	)
+//	http.Handle("/debug/introspection/", http.StripPrefix("/debug/introspection", h))
+//
+// Routes (relative to the mount point):
+//
+//	GET /                      live HTML dashboard
+//	GET /state                 latest snapshot of every component (JSON); ?since=N long-polls
+//	GET /state/{type}/{id}     latest snapshot of one component (JSON)
+//	GET /diagrams              names of the registered diagrams (JSON)
+//	GET /diagrams/{name}       rendered diagram source (text)
+//	GET /stream                Server-Sent Events stream of snapshots and events
+//	GET /healthz               health report (JSON); 503 when unhealthy
+//	GET /readyz                health report (JSON); 503 unless healthy
+package httpx
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aretw0/introspection"
+)
+
+// DefaultHeartbeat is the interval between keep-alive comments on the event stream.
+const DefaultHeartbeat = 15 * time.Second
+
+// DiagramFunc renders a diagram on demand.
+type DiagramFunc func() string
+
+// HealthFunc evaluates health on demand.
+type HealthFunc func() introspection.HealthReport
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// Handler serves introspection data over HTTP.
+type Handler struct {
+	store        *introspection.StateStore
+	snapshots    introspection.SnapshotSource
+	events       []introspection.EventSource
+	diagrams     map[string]DiagramFunc
+	diagramNames []string
+	health       HealthFunc
+	heartbeat    time.Duration
+
+	mermaidURL string
+	title      string
+	selected   string
+
+	mux *http.ServeMux
+}
+
+// WithStore serves component state from store.
+func WithStore(store *introspection.StateStore) Option {
+	return func(h *Handler) {
+		h.store = store
+	}
+}
+
+// WithSnapshots streams snapshots from src on the event stream.
+func WithSnapshots(src introspection.SnapshotSource) Option {
+	return func(h *Handler) {
+		h.snapshots = src
+	}
+}
+
+// WithEvents streams component events from srcs on the event stream.
+// Multiple sources are combined with introspection.AggregateEvents.
+func WithEvents(srcs ...introspection.EventSource) Option {
+	return func(h *Handler) {
+		h.events = append(h.events, srcs...)
+	}
+}
+
+// WithDiagram registers a named diagram. Diagrams are listed in registration order.
+func WithDiagram(name string, render DiagramFunc) Option {
+	return func(h *Handler) {
+		if _, exists := h.diagrams[name]; !exists {
+			h.diagramNames = append(h.diagramNames, name)
+		}
+		h.diagrams[name] = render
+	}
+}
+
+// WithHealth serves the report of health on /healthz and /readyz.
+func WithHealth(health HealthFunc) Option {
+	return func(h *Handler) {
+		h.health = health
+	}
+}
+
+// WithHeartbeat sets the interval between keep-alive comments on the event stream.
+// A non-positive interval disables heartbeats.
+func WithHeartbeat(d time.Duration) Option {
+	return func(h *Handler) {
+		h.heartbeat = d
+	}
+}
+
+// NewHandler creates a Handler with the given options.
+// Endpoints whose data source is not configured respond with 404 Not Found.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		diagrams:   make(map[string]DiagramFunc),
+		heartbeat:  DefaultHeartbeat,
+		mermaidURL: DefaultMermaidURL,
+		title:      DefaultDashboardTitle,
+		mux:        http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /{$}", h.handleDashboard)
+	h.mux.HandleFunc("GET /state", h.handleStateList)
+	h.mux.HandleFunc("GET /state/{type}/{id}", h.handleState)
+	h.mux.HandleFunc("GET /diagrams", h.handleDiagramList)
+	h.mux.HandleFunc("GET /diagrams/{name}", h.handleDiagram)
+	h.mux.HandleFunc("GET /stream", h.handleStream)
+	h.mux.HandleFunc("GET /healthz", h.handleHealth(introspection.HealthReport.Live))
+	h.mux.HandleFunc("GET /readyz", h.handleHealth(introspection.HealthReport.Ready))
+
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Tree renders introspection.TreeDiagram from the current state of root.
+func Tree(root introspection.Introspectable, config *introspection.DiagramConfig, opts ...introspection.MermaidOption) DiagramFunc {
+	return func() string {
+		return introspection.TreeDiagram(root.State(), config, opts...)
+	}
+}
+
+// Components renders introspection.ComponentDiagram from the current state of primary and secondary.
+func Components(primary, secondary introspection.Introspectable, config *introspection.DiagramConfig, opts ...introspection.MermaidOption) DiagramFunc {
+	return func() string {
+		return introspection.ComponentDiagram(primary.State(), secondary.State(), config, opts...)
+	}
+}
+
+// StateMachine renders introspection.StateMachineDiagram from the current state of src.
+func StateMachine(src introspection.Introspectable, config *introspection.StateMachineConfig, opts ...introspection.MermaidOption) DiagramFunc {
+	return func() string {
+		return introspection.StateMachineDiagram(src.State(), config, opts...)
+	}
+}
+
+// Machine renders m highlighting the current state of w, as reported by stateOf.
+func Machine[S any](m *introspection.StateMachine, w introspection.TypedWatcher[S], stateOf func(S) string, opts ...introspection.MermaidOption) DiagramFunc {
+	return func() string {
+		return m.Diagram(stateOf(w.State()), opts...)
+	}
+}
+
+// Sequence renders introspection.SequenceDiagram from the events currently in w.
+func Sequence(w *introspection.EventWindow, config *introspection.SequenceConfig, opts ...introspection.MermaidOption) DiagramFunc {
+	return func() string {
+		return introspection.SequenceDiagram(w.Events(), config, opts...)
+	}
+}
+
+// TreeHealth evaluates the health of the tree rooted at the current state of root.
+func TreeHealth(agg *introspection.HealthAggregator, root introspection.Introspectable) HealthFunc {
+	return func() introspection.HealthReport {
+		return agg.Evaluate(root.State())
+	}
+}
+
+// StoreHealth evaluates the health of every component in store.
+func StoreHealth(agg *introspection.HealthAggregator, store *introspection.StateStore) HealthFunc {
+	return func() introspection.HealthReport {
+		return agg.EvaluateSnapshots(store.List())
+	}
+}
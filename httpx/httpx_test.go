@@ -0,0 +1,297 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aretw0/introspection"
+)
+
+type testState struct {
+	Name     string
+	Status   string
+	Children []testState
+}
+
+type testEvent struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+}
+
+func (e testEvent) ComponentID() string   { return e.ID }
+func (e testEvent) ComponentType() string { return "worker" }
+func (e testEvent) Timestamp() time.Time  { return time.Unix(0, 0).UTC() }
+func (e testEvent) EventType() string     { return e.Kind }
+
+type testEventSource struct {
+	ch chan introspection.ComponentEvent
+}
+
+func (s *testEventSource) Events(ctx context.Context) <-chan introspection.ComponentEvent {
+	out := make(chan introspection.ComponentEvent)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case e := <-s.ch:
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+type staticState struct {
+	state any
+}
+
+func (s staticState) State() any { return s.state }
+
+func TestHandler_State(t *testing.T) {
+	store := introspection.NewStateStore()
+	store.Update(introspection.StateSnapshot{
+		ComponentType: "worker",
+		ComponentID:   "w1",
+		Payload:       testState{Name: "w1", Status: "Running"},
+	})
+
+	srv := httptest.NewServer(NewHandler(WithStore(store)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	if resp.Header.Get(versionHeader) != "1" {
+		t.Errorf("%s = %q, want 1", versionHeader, resp.Header.Get(versionHeader))
+	}
+
+	var list []snapshotJSON
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ComponentID != "w1" || list[0].ComponentType != "worker" {
+		t.Errorf("unexpected state list %+v", list)
+	}
+
+	one, err := http.Get(srv.URL + "/state/worker/w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	one.Body.Close()
+	if one.StatusCode != http.StatusOK {
+		t.Errorf("GET /state/worker/w1 status = %d", one.StatusCode)
+	}
+
+	missing, err := http.Get(srv.URL + "/state/worker/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /state/worker/nope status = %d, want 404", missing.StatusCode)
+	}
+}
+
+func TestHandler_State_LongPoll(t *testing.T) {
+	store := introspection.NewStateStore()
+	h := NewHandler(WithStore(store))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/state?since=0", nil))
+		done <- rec
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	store.Update(introspection.StateSnapshot{ComponentType: "worker", ComponentID: "w1"})
+
+	select {
+	case rec := <-done:
+		if rec.Code != http.StatusOK || rec.Header().Get(versionHeader) != "1" {
+			t.Errorf("long poll returned %d with version %q", rec.Code, rec.Header().Get(versionHeader))
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("long poll did not return after Update")
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/state?since=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandler_Diagrams(t *testing.T) {
+	root := staticState{testState{
+		Name:     "root",
+		Status:   "Running",
+		Children: []testState{{Name: "child", Status: "Failed"}},
+	}}
+
+	h := NewHandler(
+		WithDiagram("tree", Tree(root, nil)),
+		WithDiagram("custom", func() string { return "graph LR\n" }),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/diagrams", nil))
+	if got := strings.Join(strings.Fields(rec.Body.String()), ""); got != `["tree","custom"]` {
+		t.Errorf("GET /diagrams = %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/diagrams/tree", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"graph TD", "root", "child", "failed"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("tree diagram missing %q", want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/diagrams/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /diagrams/missing status = %d, want 404", rec.Code)
+	}
+}
+
+func TestHandler_MachineDiagram(t *testing.T) {
+	m := &introspection.StateMachine{
+		States:      []introspection.State{{Name: "Idle", Initial: true}, {Name: "Busy"}},
+		Transitions: []introspection.Transition{{From: "Idle", To: "Busy", Event: "work"}},
+	}
+	b := introspection.NewBroadcaster("worker", "w1", "Idle")
+	defer b.Close()
+
+	h := NewHandler(WithDiagram("lifecycle", Machine(m, b, func(s string) string { return s })))
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/diagrams/lifecycle", nil))
+		return rec.Body.String()
+	}
+
+	if body := get(); !strings.Contains(body, "class Idle active") {
+		t.Errorf("diagram should highlight Idle\n%s", body)
+	}
+	b.Publish("Idle", "Busy")
+	if body := get(); !strings.Contains(body, "class Busy active") {
+		t.Errorf("diagram should highlight Busy\n%s", body)
+	}
+}
+
+func TestHandler_Health(t *testing.T) {
+	state := testState{Name: "root", Status: "Running", Children: []testState{{Name: "child", Status: "Running"}}}
+	root := &staticState{state}
+	h := NewHandler(WithHealth(TreeHealth(introspection.NewHealthAggregator(), root)))
+
+	get := func(path string) (int, introspection.HealthReport) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var report introspection.HealthReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return rec.Code, report
+	}
+
+	if code, report := get("/readyz"); code != http.StatusOK || report.Status != introspection.HealthHealthy {
+		t.Errorf("GET /readyz = %d %+v", code, report)
+	}
+
+	// A failed child degrades the root: still live, but not ready.
+	state.Children[0].Status = "Failed"
+	root.state = state
+	if code, report := get("/healthz"); code != http.StatusOK || report.Status != introspection.HealthDegraded {
+		t.Errorf("GET /healthz = %d %+v", code, report)
+	}
+	code, report := get("/readyz")
+	if code != http.StatusServiceUnavailable || len(report.Children) != 1 || report.Children[0].Status != introspection.HealthUnhealthy {
+		t.Errorf("GET /readyz = %d %+v", code, report)
+	}
+}
+
+func TestHandler_NotConfigured(t *testing.T) {
+	h := NewHandler()
+
+	for _, path := range []string{"/state", "/state/worker/w1", "/stream", "/healthz", "/readyz"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestHandler_Stream(t *testing.T) {
+	broadcaster := introspection.NewBroadcaster("worker", "w1", testState{Status: "Starting"})
+	registry := introspection.NewRegistry()
+	if err := introspection.RegisterWatcher[testState](registry, "w1", broadcaster); err != nil {
+		t.Fatal(err)
+	}
+	events := &testEventSource{ch: make(chan introspection.ComponentEvent, 1)}
+
+	srv := httptest.NewServer(NewHandler(WithSnapshots(registry), WithEvents(events)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	// Give the registry subscription time to attach before publishing.
+	time.Sleep(50 * time.Millisecond)
+	broadcaster.Publish(testState{Status: "Starting"}, testState{Status: "Running"})
+	events.ch <- testEvent{ID: "w1", Kind: "started"}
+
+	seen := map[string]string{}
+	scanner := bufio.NewScanner(resp.Body)
+	var name string
+	for len(seen) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			seen[name] = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	if !strings.Contains(seen["snapshot"], `"Status":"Running"`) {
+		t.Errorf("snapshot event = %q", seen["snapshot"])
+	}
+	if !strings.Contains(seen["event"], `"eventType":"started"`) || !strings.Contains(seen["event"], `"kind":"started"`) {
+		t.Errorf("component event = %q", seen["event"])
+	}
+}
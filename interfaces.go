@@ -19,6 +19,23 @@ type Component interface {
 	ComponentType() string
 }
 
+// TreeNode is an optional interface for hierarchical state that diagram builders
+// prefer over reflection. It suits trees that are materialized lazily or guarded by
+// locks, where copying into a struct with a Children slice would be costly or racy.
+type TreeNode interface {
+	// NodeName returns the node's display name.
+	NodeName() string
+
+	// NodeStatus returns the node's lifecycle status (e.g., "Running", "Failed").
+	NodeStatus() string
+
+	// NodeMetadata returns optional key/value metadata, such as a "type" used for styling.
+	NodeMetadata() map[string]string
+
+	// NodeChildren returns the node's children in display order.
+	NodeChildren() []TreeNode
+}
+
 // TypedWatcher provides type-safe state watching for a specific state type S.
 // Implementations can return their domain-specific state without any type assertions.
 type TypedWatcher[S any] interface {
@@ -36,3 +53,11 @@ type EventSource interface {
 	// The channel is closed when the provided context is cancelled.
 	Events(ctx context.Context) <-chan ComponentEvent
 }
+
+// SnapshotSource provides a stream of state snapshots for aggregation.
+// WatcherAdapter, Aggregator and Registry all implement it.
+type SnapshotSource interface {
+	// Snapshots returns a channel of state snapshots.
+	// The channel is closed when the provided context is cancelled.
+	Snapshots(ctx context.Context) <-chan StateSnapshot
+}
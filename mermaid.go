@@ -1,9 +1,13 @@
 package introspection
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
+	"unicode"
 )
 
 // MermaidOption configures the rendering behavior.
@@ -11,9 +15,31 @@ type MermaidOption func(*MermaidOptions)
 
 // MermaidOptions holds Mermaid rendering options.
 type MermaidOptions struct {
-	Styles string // Custom Mermaid class definitions
+	Styles      string // Custom Mermaid class definitions
+	TrustedHTML bool   // Render labels without escaping HTML (see WithTrustedHTML)
+
+	// Layout
+	Direction          Direction            // Diagram direction (default: top to bottom, written "TD")
+	SubgraphDirections map[string]Direction // Directions inside subgraphs and composite states, by Subgraph.ID
+	Flowchart          bool                 // Use the "flowchart" keyword instead of "graph"
+
+	// Init directive; zero values are left to Mermaid's configuration
+	Theme       string // e.g. "default", "dark", "forest", "neutral"
+	Curve       string // Flowchart edge curve, e.g. "basis", "linear", "step"
+	NodeSpacing int    // Flowchart spacing between nodes of the same rank, in pixels
+	RankSpacing int    // Flowchart spacing between ranks, in pixels
 }
 
+// Direction is the layout direction of a Mermaid flowchart or state diagram.
+type Direction string
+
+const (
+	DirectionTB Direction = "TB" // Top to bottom
+	DirectionBT Direction = "BT" // Bottom to top
+	DirectionLR Direction = "LR" // Left to right
+	DirectionRL Direction = "RL" // Right to left
+)
+
 // DefaultStyles returns the standard Mermaid class definitions for lifecycle diagrams.
 func DefaultStyles() string {
 	return `    classDef created fill:#f8f9fa,stroke:#dee2e6,color:#6c757d;
@@ -42,6 +68,99 @@ func WithStyles(styles string) MermaidOption {
 	}
 }
 
+// WithDirection sets the direction of the diagram, e.g. DirectionLR for wide trees.
+func WithDirection(d Direction) MermaidOption {
+	return func(o *MermaidOptions) {
+		o.Direction = d
+	}
+}
+
+// WithSubgraphDirection sets the direction inside the subgraph or composite state
+// with the given ID, e.g. "secondary" for the tree of a ComponentDiagram.
+// Mermaid ignores it for flowchart subgraphs whose nodes link outside the subgraph.
+func WithSubgraphDirection(id string, d Direction) MermaidOption {
+	return func(o *MermaidOptions) {
+		if o.SubgraphDirections == nil {
+			o.SubgraphDirections = make(map[string]Direction)
+		}
+		o.SubgraphDirections[id] = d
+	}
+}
+
+// WithFlowchartKeyword starts flowcharts with "flowchart" instead of "graph",
+// which newer Mermaid versions render with the flowchart-v2 renderer.
+func WithFlowchartKeyword() MermaidOption {
+	return func(o *MermaidOptions) {
+		o.Flowchart = true
+	}
+}
+
+// WithTheme sets the Mermaid theme in the init directive.
+func WithTheme(theme string) MermaidOption {
+	return func(o *MermaidOptions) {
+		o.Theme = theme
+	}
+}
+
+// WithCurve sets the flowchart edge curve in the init directive.
+func WithCurve(curve string) MermaidOption {
+	return func(o *MermaidOptions) {
+		o.Curve = curve
+	}
+}
+
+// WithSpacing sets the flowchart node and rank spacing in the init directive.
+func WithSpacing(node, rank int) MermaidOption {
+	return func(o *MermaidOptions) {
+		o.NodeSpacing, o.RankSpacing = node, rank
+	}
+}
+
+// writeInit writes the init directive, if any option needs one.
+func (o *MermaidOptions) writeInit(sb *strings.Builder) {
+	init := map[string]any{}
+	if o.Theme != "" {
+		init["theme"] = o.Theme
+	}
+	flowchart := map[string]any{}
+	if o.Curve != "" {
+		flowchart["curve"] = o.Curve
+	}
+	if o.NodeSpacing > 0 {
+		flowchart["nodeSpacing"] = o.NodeSpacing
+	}
+	if o.RankSpacing > 0 {
+		flowchart["rankSpacing"] = o.RankSpacing
+	}
+	if len(flowchart) > 0 {
+		init["flowchart"] = flowchart
+	}
+	if len(init) == 0 {
+		return
+	}
+	directive, _ := json.Marshal(init) // Maps of strings and ints always marshal
+	sb.WriteString("%%{init: " + string(directive) + "}%%\n")
+}
+
+// writeFlowchartHeader writes the init directive and the flowchart declaration.
+func (o *MermaidOptions) writeFlowchartHeader(sb *strings.Builder) {
+	o.writeInit(sb)
+	keyword := "graph"
+	if o.Flowchart {
+		keyword = "flowchart"
+	}
+	sb.WriteString(keyword + " " + cmp.Or(string(o.Direction), "TD") + "\n")
+}
+
+// WithTrustedHTML renders labels, titles and notes without escaping HTML tags and
+// Mermaid entity codes, so custom labelers can emit arbitrary markup. Only use it when
+// no label contains user-controlled text such as component names, metadata or reasons.
+func WithTrustedHTML() MermaidOption {
+	return func(o *MermaidOptions) {
+		o.TrustedHTML = true
+	}
+}
+
 // DiagramConfig holds configuration for customizing diagram rendering.
 type DiagramConfig struct {
 	// Primary component configuration
@@ -61,8 +180,43 @@ type DiagramConfig struct {
 	NodeLabeler NodeLabelFunc // Custom function to build node labels
 
 	// Primary node customization
+	// When nil, the primary node is styled and labeled from its Enabled, Stopping,
+	// Stopped and Reason fields, honoring Fields.
 	PrimaryNodeStyler  PrimaryNodeStyleFunc // Custom function to determine CSS class for primary component
 	PrimaryNodeLabeler PrimaryNodeLabelFunc // Custom function to build HTML label for primary component
+
+	// Fields names the struct fields read via reflection, for types that do not use
+	// the default field names or introspect struct tags.
+	Fields FieldMapping
+
+	// NodeID derives the IDs of tree nodes below the root (default: PositionalNodeIDs).
+	// IDs are always made Mermaid-safe, and made unique with "_2", "_3", ... suffixes.
+	NodeID NodeIDFunc
+}
+
+// FieldMapping names the struct fields that diagram reflection reads.
+// Empty entries fall back to the field tagged `introspect:"<key>"` (e.g. `introspect:"status"`),
+// then to the default field name shown in parentheses.
+//
+//	type Task struct {
+//		ID       string            `introspect:"name"`
+//		Phase    string            `introspect:"status"`
+//		Labels   map[string]string `introspect:"metadata"`
+//		SubTasks []Task            `introspect:"children"`
+//	}
+type FieldMapping struct {
+	// Tree nodes
+	Name     string // Node name, tag "name" (Name)
+	Status   string // Node status, tag "status" (Status)
+	PID      string // Process ID, tag "pid" (PID)
+	Metadata string // map[string]string metadata, tag "metadata" (Metadata)
+	Children string // Child nodes slice, tag "children" (Children)
+
+	// Primary component, used when PrimaryNodeStyler or PrimaryNodeLabeler is nil
+	Enabled  string // Tag "enabled" (Enabled)
+	Stopping string // Tag "stopping" (Stopping)
+	Stopped  string // Tag "stopped" (Stopped)
+	Reason   string // Tag "reason" (Reason)
 }
 
 // NodeStyleFunc is a function that returns icon, shape start, shape end, and CSS class for a node.
@@ -77,19 +231,38 @@ type PrimaryNodeStyleFunc func(state any) (cssClass string)
 // PrimaryNodeLabelFunc builds the HTML label for the primary component.
 type PrimaryNodeLabelFunc func(state any) string
 
+// NodeIDFunc returns the ID of a tree node from its parent's ID, its position among
+// its siblings, its name and the node itself.
+type NodeIDFunc func(parentID string, index int, name string, node any) string
+
+// PositionalNodeIDs numbers nodes by position, e.g. "secondary_0_1". Inserting a child
+// renumbers its later siblings and their descendants.
+func PositionalNodeIDs(parentID string, index int, name string, node any) string {
+	return fmt.Sprintf("%s_%d", parentID, index)
+}
+
+// NameNodeIDs derives node IDs from node names, so they stay stable when siblings are
+// added or removed. Nodes without a name fall back to PositionalNodeIDs; nodes whose
+// names collide are suffixed in depth-first order.
+func NameNodeIDs(parentID string, index int, name string, node any) string {
+	if name == "" {
+		return PositionalNodeIDs(parentID, index, name, node)
+	}
+	return name
+}
+
 // DefaultDiagramConfig returns a generic configuration with no domain-specific terms.
 func DefaultDiagramConfig() *DiagramConfig {
 	return &DiagramConfig{
-		PrimaryID:          "primary",
-		PrimaryLabel:       "Primary Component",
-		PrimaryNodeLabel:   "⚡ Component",
-		SecondaryID:        "secondary",
-		SecondaryLabel:     "Secondary Component",
-		ConnectionLabel:    "manages",
-		NodeStyler:         defaultNodeStyler,
-		NodeLabeler:        defaultNodeLabeler,
-		PrimaryNodeStyler:  defaultPrimaryNodeStyler,
-		PrimaryNodeLabeler: defaultPrimaryNodeLabeler,
+		PrimaryID:        "primary",
+		PrimaryLabel:     "Primary Component",
+		PrimaryNodeLabel: "⚡ Component",
+		SecondaryID:      "secondary",
+		SecondaryLabel:   "Secondary Component",
+		ConnectionLabel:  "manages",
+		NodeStyler:       defaultNodeStyler,
+		NodeLabeler:      defaultNodeLabeler,
+		NodeID:           PositionalNodeIDs,
 	}
 }
 
@@ -137,57 +310,51 @@ func defaultNodeLabeler(name, status string, pid int, metadata map[string]string
 	return strings.Join(parts, "<br/>")
 }
 
-// defaultPrimaryNodeStyler provides default CSS class styling for primary components.
-// It uses reflection to find common state fields (Enabled, Stopping, Stopped).
-func defaultPrimaryNodeStyler(state any) string {
+// primaryFields holds the fields of a primary component extracted via reflection.
+type primaryFields struct {
+	enabled  bool
+	stopping bool
+	stopped  bool
+	reason   string
+}
+
+func readPrimaryFields(state any, fields FieldMapping) primaryFields {
 	v := reflect.ValueOf(state)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
+	return primaryFields{
+		enabled:  boolValue(mappedField(v, fieldEnabled, fields.Enabled)),
+		stopping: boolValue(mappedField(v, fieldStopping, fields.Stopping)),
+		stopped:  boolValue(mappedField(v, fieldStopped, fields.Stopped)),
+		reason:   stringValue(mappedField(v, fieldReason, fields.Reason)),
+	}
+}
 
-	enabled := getBoolField(v, "Enabled")
-	stopping := getBoolField(v, "Stopping")
-	stopped := getBoolField(v, "Stopped")
-
-	if !enabled {
+func (p primaryFields) class() string {
+	if !p.enabled {
 		return "stopped"
-	} else if stopped {
+	} else if p.stopped {
 		return "stopped"
-	} else if stopping {
+	} else if p.stopping {
 		return "pending"
 	}
 	return "running"
 }
 
-// defaultPrimaryNodeLabeler provides default HTML label formatting for primary components.
-// It uses reflection to find common state fields (Enabled, Stopping, Stopped, Reason).
-func defaultPrimaryNodeLabeler(state any) string {
-	v := reflect.ValueOf(state)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-
-	enabled := getBoolField(v, "Enabled")
-	stopping := getBoolField(v, "Stopping")
-	stopped := getBoolField(v, "Stopped")
-	reason := getStringField(v, "Reason")
-
+func (p primaryFields) label() string {
 	statusMode := "Running"
-	if !enabled {
+	if !p.enabled {
 		statusMode = "Disabled"
-	} else if stopped {
+	} else if p.stopped {
 		statusMode = "Stopped"
-	} else if stopping {
+	} else if p.stopping {
 		statusMode = "Stopping"
 	}
 
-	if reason == "" {
-		reason = "None"
-	}
-
 	label := fmt.Sprintf("Mode: %s", statusMode)
-	if reason != "None" {
-		label += fmt.Sprintf("<br/>Reason: %s", reason)
+	if p.reason != "" && p.reason != "None" {
+		label += fmt.Sprintf("<br/>Reason: %s", p.reason)
 	}
 
 	return label
@@ -196,54 +363,14 @@ func defaultPrimaryNodeLabeler(state any) string {
 // ComponentDiagram renders a customizable topology diagram with two components.
 // This is a generic version that allows full customization of labels and styling.
 func ComponentDiagram(primary, secondary any, config *DiagramConfig, opts ...MermaidOption) string {
-	if config == nil {
-		config = DefaultDiagramConfig()
-	}
-
-	options := &MermaidOptions{Styles: DefaultStyles()}
-	for _, opt := range opts {
-		opt(options)
-	}
-
-	var sb strings.Builder
-	sb.WriteString("graph TD\n")
-
-	// 1. Primary Component Subgraph
-	sb.WriteString(fmt.Sprintf("    subgraph %s_graph [%s]\n", config.PrimaryID, config.PrimaryLabel))
-	renderGenericFragment(&sb, primary, config.PrimaryID, config.PrimaryNodeLabel, "        ", config.PrimaryNodeStyler, config.PrimaryNodeLabeler)
-	sb.WriteString("    end\n\n")
-
-	// 2. Secondary Component Subgraph
-	sb.WriteString(fmt.Sprintf("    subgraph %s_graph [%s]\n", config.SecondaryID, config.SecondaryLabel))
-	renderGenericTree(&sb, secondary, config.SecondaryID, config.NodeStyler, config.NodeLabeler, "        ")
-	sb.WriteString("    end\n\n")
-
-	// 3. Connection
-	sb.WriteString(fmt.Sprintf("    %s -- %s --> %s\n", config.PrimaryID, config.ConnectionLabel, config.SecondaryID))
-
-	// 4. Styles
-	sb.WriteString(options.Styles)
-
-	return sb.String()
+	return NewMermaidRenderer(opts...).Render(BuildComponentGraph(primary, secondary, config, opts...))
 }
 
 // TreeDiagram returns a generic Mermaid diagram representing a hierarchical tree structure.
-// The structure is introspected via reflection using common field names (Name, Status, PID, Metadata, Children).
+// The structure is introspected via reflection using common field names (Name, Status, PID, Metadata, Children),
+// introspect struct tags or config.Fields.
 func TreeDiagram(root any, config *DiagramConfig, opts ...MermaidOption) string {
-	if config == nil {
-		config = DefaultDiagramConfig()
-	}
-
-	options := &MermaidOptions{Styles: DefaultStyles()}
-	for _, opt := range opts {
-		opt(options)
-	}
-
-	var sb strings.Builder
-	sb.WriteString("graph TD\n")
-	sb.WriteString(options.Styles)
-	renderGenericTree(&sb, root, config.SecondaryID, config.NodeStyler, config.NodeLabeler, "    ")
-	return sb.String()
+	return NewMermaidRenderer(opts...).Render(BuildTreeGraph(root, config, opts...))
 }
 
 // StateMachineConfig configures generic Mermaid state diagram rendering.
@@ -277,113 +404,378 @@ func DefaultStateMachineConfig() *StateMachineConfig {
 // StateMachineDiagram renders a customizable Mermaid state diagram.
 // It introspects the state object via reflection to find relevant fields.
 func StateMachineDiagram(state any, config *StateMachineConfig, opts ...MermaidOption) string {
-	if config == nil {
-		config = DefaultStateMachineConfig()
+	return NewMermaidRenderer(opts...).Render(BuildStateMachineGraph(state, config, opts...))
+}
+
+// MermaidRenderer renders a Graph as Mermaid source.
+// Flowchart graphs become "graph TD" diagrams and state graphs become "stateDiagram-v2";
+// the options set the direction, keyword and init directive.
+//
+// Labels, subgraph titles, edge labels and notes are escaped so that user-controlled
+// text cannot break the diagram or inject markup: quotes and "#" become Mermaid entity
+// codes, "<" becomes one when it could open an HTML tag, and newlines become <br/>.
+// The <b>, <i> and <br/> tags emitted by the diagram builders are kept.
+type MermaidRenderer struct {
+	options MermaidOptions
+}
+
+// NewMermaidRenderer returns a MermaidRenderer configured by opts. The zero
+// MermaidRenderer is equivalent to NewMermaidRenderer().
+func NewMermaidRenderer(opts ...MermaidOption) MermaidRenderer {
+	return MermaidRenderer{options: *newMermaidOptions(opts)}
+}
+
+// Render implements Renderer.
+func (r MermaidRenderer) Render(g *Graph) string {
+	var sb strings.Builder
+	if g.Kind == StateGraph {
+		r.renderStates(&sb, g)
+		sb.WriteString(g.Styles)
+	} else {
+		r.renderFlowchart(&sb, g)
 	}
+	return sb.String()
+}
 
-	options := &MermaidOptions{Styles: DefaultStyles()}
-	for _, opt := range opts {
-		opt(options)
+// renderFlowchart writes subgraphs, then top-level nodes and edges.
+// Subgraph IDs are suffixed with "_graph" so they never collide with node IDs.
+// Class definitions follow the header in plain trees and end diagrams with subgraphs,
+// where TreeDiagram and ComponentDiagram have always put them.
+func (r MermaidRenderer) renderFlowchart(sb *strings.Builder, g *Graph) {
+	r.options.writeFlowchartHeader(sb)
+	if len(g.Subgraphs) == 0 {
+		sb.WriteString(g.Styles)
+	}
+	r.renderSubgraphs(sb, g.Subgraphs, "    ")
+	r.renderFlowchartBody(sb, g.Nodes, g.Edges, "    ")
+	if len(g.Subgraphs) > 0 {
+		sb.WriteString(g.Styles)
 	}
+}
 
-	var sb strings.Builder
-	v := reflect.ValueOf(state)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+func (r MermaidRenderer) renderSubgraphs(sb *strings.Builder, subgraphs []*Subgraph, indent string) {
+	for _, sg := range subgraphs {
+		sb.WriteString(fmt.Sprintf("%ssubgraph %s_graph [%s]\n", indent, mermaidID(sg.ID), r.bare(sg.Label)))
+		writeDirection(sb, r.options.SubgraphDirections[sg.ID], indent+"    ")
+		r.renderSubgraphs(sb, sg.Subgraphs, indent+"    ")
+		r.renderFlowchartBody(sb, sg.Nodes, sg.Edges, indent+"    ")
+		sb.WriteString(indent + "end\n\n")
+	}
+}
+
+// renderFlowchartBody writes nodes depth-first: each node is followed, per outgoing edge,
+// by the subtree the edge leads to and then the edge itself. Edges leaving nodes outside
+// the body follow at the end.
+func (r MermaidRenderer) renderFlowchartBody(sb *strings.Builder, nodes []*Node, edges []*Edge, indent string) {
+	byID := make(map[string]*Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	outgoing := make(map[string][]*Edge)
+	var remaining []*Edge
+	for _, e := range edges {
+		if byID[e.From] != nil {
+			outgoing[e.From] = append(outgoing[e.From], e)
+		} else {
+			remaining = append(remaining, e)
+		}
+	}
+
+	written := make(map[string]bool, len(nodes))
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		written[n.ID] = true
+		r.writeFlowchartNode(sb, n, indent)
+		for _, e := range outgoing[n.ID] {
+			if to := byID[e.To]; to != nil && !e.Back && !written[e.To] {
+				visit(to)
+			}
+			r.writeFlowchartEdge(sb, e, indent)
+		}
+	}
+	for _, n := range nodes {
+		if !written[n.ID] {
+			visit(n)
+		}
 	}
+	for _, e := range remaining {
+		r.writeFlowchartEdge(sb, e, indent)
+	}
+}
 
-	forceExitThreshold := getIntField(v, "ForceExitThreshold")
-	stopping := getBoolField(v, "Stopping")
-	stopped := getBoolField(v, "Stopped")
+func (r MermaidRenderer) writeFlowchartNode(sb *strings.Builder, n *Node, indent string) {
+	id := mermaidID(n.ID)
+	sb.WriteString(fmt.Sprintf("%s%s%s\"%s\"%s", indent, id, n.ShapeStart, r.text(n.Label), n.ShapeEnd))
+	if len(n.Classes) > 0 {
+		sb.WriteString(":::" + mermaidClass(n.Classes[0]))
+	}
+	sb.WriteString("\n")
+	for i, class := range n.Classes {
+		if i > 0 {
+			sb.WriteString(fmt.Sprintf("%sclass %s %s\n", indent, id, mermaidClass(class)))
+		}
+	}
+}
 
+func (r MermaidRenderer) writeFlowchartEdge(sb *strings.Builder, e *Edge, indent string) {
+	from, to := mermaidID(e.From), mermaidID(e.To)
+	switch {
+	case e.Back && e.Label != "":
+		sb.WriteString(fmt.Sprintf("%s%s -. %s .-> %s\n", indent, from, r.bare(e.Label), to))
+	case e.Back:
+		sb.WriteString(fmt.Sprintf("%s%s -.-> %s\n", indent, from, to))
+	case e.Label != "":
+		sb.WriteString(fmt.Sprintf("%s%s -- %s --> %s\n", indent, from, r.bare(e.Label), to))
+	default:
+		sb.WriteString(fmt.Sprintf("%s%s --> %s\n", indent, from, to))
+	}
+}
+
+// renderStates writes states, transitions, notes and state classes.
+// Subgraphs become composite states.
+func (r MermaidRenderer) renderStates(sb *strings.Builder, g *Graph) {
+	r.options.writeInit(sb)
 	sb.WriteString("stateDiagram-v2\n")
-	sb.WriteString(fmt.Sprintf("    [*] --> %s\n", config.InitialState))
-	sb.WriteString(fmt.Sprintf("    %s --> %s: %s\n", config.InitialState, config.GracefulState, config.InitialToGraceful))
-
-	// Add note if provided
-	if config.NoteGenerator != nil {
-		note := config.NoteGenerator(state)
-		if note != "" {
-			sb.WriteString(fmt.Sprintf("    note right of %s\n", config.GracefulState))
-			sb.WriteString(note)
-			sb.WriteString("    end note\n")
+	writeDirection(sb, r.options.Direction, "    ")
+
+	referenced := map[string]bool{}
+	for _, e := range g.AllEdges() {
+		referenced[e.From] = true
+		referenced[e.To] = true
+	}
+
+	// A note follows the first top-level transition into its state, where
+	// StateMachineDiagram has always written it; other notes follow all transitions.
+	pending := make(map[string]bool, len(g.Notes))
+	for _, note := range g.Notes {
+		pending[note.Target] = true
+	}
+	r.renderStateBody(sb, g.Nodes, g.Subgraphs, g.Edges, referenced, "    ", func(to string) {
+		if pending[to] {
+			delete(pending, to)
+			r.writeNotes(sb, g.Notes, to)
+		}
+	})
+	for _, note := range g.Notes {
+		if pending[note.Target] {
+			delete(pending, note.Target)
+			r.writeNotes(sb, g.Notes, note.Target)
 		}
 	}
 
-	if forceExitThreshold > 0 {
-		sb.WriteString(fmt.Sprintf("    %s --> %s: %s x%d\n", config.GracefulState, config.ForcedState, config.GracefulToForced, forceExitThreshold))
-		sb.WriteString(fmt.Sprintf("    %s --> [*]: Exit\n", config.ForcedState))
+	for _, n := range g.AllNodes() {
+		for _, class := range n.Classes {
+			sb.WriteString(fmt.Sprintf("    class %s %s\n", mermaidStateID(n.ID), mermaidClass(class)))
+		}
 	}
+}
 
-	sb.WriteString(fmt.Sprintf("    %s --> [*]: %s\n", config.GracefulState, config.GracefulToFinal))
+// renderStateBody declares labeled or otherwise unreferenced states, composite states, then transitions.
+// afterEdge, if set, is called after each transition with its target.
+func (r MermaidRenderer) renderStateBody(sb *strings.Builder, nodes []*Node, subgraphs []*Subgraph, edges []*Edge, referenced map[string]bool, indent string, afterEdge func(to string)) {
+	for _, n := range nodes {
+		id, label := mermaidID(n.ID), cmp.Or(n.Label, n.ID)
+		switch {
+		case isPseudoState(n.ID):
+		case label != id:
+			sb.WriteString(fmt.Sprintf("%sstate \"%s\" as %s\n", indent, r.text(label), id))
+		case !referenced[n.ID]:
+			sb.WriteString(fmt.Sprintf("%s%s\n", indent, id))
+		}
+	}
 
-	// Apply state classes
-	if stopped {
-		sb.WriteString("    class [*] stopped\n")
-	} else if stopping {
-		sb.WriteString(fmt.Sprintf("    class %s stopping\n", config.GracefulState))
-	} else {
-		sb.WriteString(fmt.Sprintf("    class %s running\n", config.InitialState))
+	for _, sg := range subgraphs {
+		id, label := mermaidID(sg.ID), cmp.Or(sg.Label, sg.ID)
+		if label != id {
+			sb.WriteString(fmt.Sprintf("%sstate \"%s\" as %s\n", indent, r.text(label), id))
+		}
+		sb.WriteString(fmt.Sprintf("%sstate %s {\n", indent, id))
+		writeDirection(sb, r.options.SubgraphDirections[sg.ID], indent+"    ")
+		r.renderStateBody(sb, sg.Nodes, sg.Subgraphs, sg.Edges, referenced, indent+"    ", nil)
+		sb.WriteString(indent + "}\n")
 	}
 
-	sb.WriteString(options.Styles)
-	return sb.String()
+	for _, e := range edges {
+		if e.Label != "" {
+			sb.WriteString(fmt.Sprintf("%s%s --> %s: %s\n", indent, mermaidStateID(e.From), mermaidStateID(e.To), r.text(e.Label)))
+		} else {
+			sb.WriteString(fmt.Sprintf("%s%s --> %s\n", indent, mermaidStateID(e.From), mermaidStateID(e.To)))
+		}
+		if afterEdge != nil {
+			afterEdge(e.To)
+		}
+	}
 }
 
-// renderGenericFragment renders a single component node (for primary/controller type components).
-// If styler and labeler are provided, uses them. Otherwise, uses default reflection-based behavior.
-func renderGenericFragment(sb *strings.Builder, comp any, id, labelPrefix, indent string, styler PrimaryNodeStyleFunc, labeler PrimaryNodeLabelFunc) {
-	// Use provided functions or defaults
-	if styler == nil {
-		styler = defaultPrimaryNodeStyler
+// writeNotes writes the notes attached to target.
+func (r MermaidRenderer) writeNotes(sb *strings.Builder, notes []*Note, target string) {
+	for _, note := range notes {
+		if note.Target != target {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("    note %s %s\n", note.Position, mermaidStateID(note.Target)))
+		text := r.note(note.Text)
+		sb.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("    end note\n")
 	}
-	if labeler == nil {
-		labeler = defaultPrimaryNodeLabeler
+}
+
+// writeDirection writes a direction statement, unless d is empty.
+func writeDirection(sb *strings.Builder, d Direction, indent string) {
+	if d != "" {
+		sb.WriteString(indent + "direction " + string(d) + "\n")
 	}
+}
 
-	// Get styling and label from the custom functions
-	statusClass := styler(comp)
-	labelContent := labeler(comp)
+// text escapes a single-line label, title or edge label.
+func (r MermaidRenderer) text(s string) string {
+	if r.options.TrustedHTML {
+		s = strings.ReplaceAll(s, `"`, "#quot;")
+		return strings.ReplaceAll(s, "\n", "<br/>")
+	}
+	var sb strings.Builder
+	for {
+		loc := mermaidMarkup.FindStringIndex(s)
+		if loc == nil {
+			break
+		}
+		sb.WriteString(escapeMermaidText(s[:loc[0]], "<br/>"))
+		sb.WriteString(s[loc[0]:loc[1]])
+		s = s[loc[1]:]
+	}
+	sb.WriteString(escapeMermaidText(s, "<br/>"))
+	return sb.String()
+}
 
-	// Build the full label with the prefix
-	label := fmt.Sprintf("<b>%s</b><br/>%s", labelPrefix, labelContent)
+// bare escapes a subgraph title or edge label, which Mermaid reads unquoted.
+// Anything but plain words is quoted, so brackets and arrows cannot end it early.
+func (r MermaidRenderer) bare(s string) string {
+	plain := strings.IndexFunc(s, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" _.,:'", c)
+	}) < 0
+	if plain {
+		return s
+	}
+	return `"` + r.text(s) + `"`
+}
 
-	sb.WriteString(fmt.Sprintf("%s%s[\"%s\"]:::signal\n", indent, id, label))
-	sb.WriteString(fmt.Sprintf("%sclass %s %s\n", indent, id, statusClass))
+// note escapes a multi-line note body. Lines are kept, and a line reading "end note"
+// cannot close the note early.
+func (r MermaidRenderer) note(s string) string {
+	if !r.options.TrustedHTML {
+		s = escapeMermaidText(s, "\n")
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "end note" {
+			lines[i] = strings.Replace(line, "end", "#101;nd", 1)
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
-// renderGenericTree renders a hierarchical tree structure.
-func renderGenericTree(sb *strings.Builder, root any, rootID string, styler NodeStyleFunc, labeler NodeLabelFunc, indent string) {
-	renderGenericNode(sb, root, rootID, styler, labeler, indent)
+// mermaidMarkup matches the HTML tags the diagram builders emit in labels.
+var mermaidMarkup = regexp.MustCompile(`<(?:/?[bi]|br ?/?)>`)
+
+// escapeMermaidText replaces the characters of s that Mermaid or a browser would
+// interpret with Mermaid entity codes, and newlines with newline.
+func escapeMermaidText(s, newline string) string {
+	var sb strings.Builder
+	for i, c := range s {
+		switch c {
+		case '#':
+			sb.WriteString("#35;")
+		case '"':
+			sb.WriteString("#quot;")
+		case '<':
+			if opensTag(s[i+1:]) {
+				sb.WriteString("#lt;")
+			} else {
+				sb.WriteRune(c)
+			}
+		case '\n':
+			sb.WriteString(newline)
+		case '\r':
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
 }
 
-// renderGenericNode renders a single node and recursively renders its children.
-func renderGenericNode(sb *strings.Builder, node any, id string, styler NodeStyleFunc, labeler NodeLabelFunc, indent string) {
-	v := reflect.ValueOf(node)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+// opensTag reports whether rest, following a "<", could be read as an HTML tag,
+// comment or processing instruction.
+func opensTag(rest string) bool {
+	if rest == "" {
+		return false
 	}
+	c := rest[0]
+	return c == '/' || c == '!' || c == '?' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
 
-	name := getStringField(v, "Name")
-	status := getStringField(v, "Status")
-	pid := getIntField(v, "PID")
-	metadata := getMapField(v, "Metadata")
-	children := getSliceField(v, "Children")
+// isPseudoState reports whether id is an initial or final pseudo-state.
+func isPseudoState(id string) bool {
+	return isStartState(id) || isEndState(id)
+}
+
+func isStartState(id string) bool {
+	return strings.HasPrefix(id, StartStateID)
+}
+
+func isEndState(id string) bool {
+	return strings.HasPrefix(id, EndStateID)
+}
 
-	icon, shapeStart, shapeEnd, idClass := styler(metadata)
-	label := labeler(name, status, pid, metadata, icon)
+// mermaidStateID maps pseudo-state IDs to Mermaid's [*] and escapes other IDs.
+func mermaidStateID(id string) string {
+	if isPseudoState(id) {
+		return "[*]"
+	}
+	return mermaidID(id)
+}
 
-	sb.WriteString(fmt.Sprintf("%s%s%s\"%s\"%s:::%s\n", indent, id, shapeStart, label, shapeEnd, idClass))
+// mermaidKeywords cannot be used as node IDs.
+var mermaidKeywords = map[string]bool{
+	"end": true, "graph": true, "flowchart": true, "subgraph": true, "direction": true,
+	"style": true, "linkStyle": true, "class": true, "classDef": true, "click": true,
+	"state": true, "note": true, "default": true,
+}
 
-	statusClass := strings.ToLower(status)
-	if statusClass == "" {
-		statusClass = "pending"
+// mermaidID returns id with every character other than ASCII letters, digits and
+// underscores replaced by an underscore. Keywords get a trailing underscore.
+func mermaidID(id string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, id)
+	if safe == "" || mermaidKeywords[safe] {
+		safe += "_"
 	}
-	sb.WriteString(fmt.Sprintf("%sclass %s %s\n", indent, id, statusClass))
+	return safe
+}
 
-	for i, child := range children {
-		childID := fmt.Sprintf("%s_%d", id, i)
-		renderGenericNode(sb, child, childID, styler, labeler, indent)
-		sb.WriteString(fmt.Sprintf("%s%s --> %s\n", indent, id, childID))
+// mermaidClass returns class with every character other than ASCII letters, digits,
+// underscores and hyphens replaced by an underscore. Classes derived from a status
+// are user-controlled, so they must not reach the output verbatim.
+func mermaidClass(class string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, class)
+}
+
+// newMermaidOptions applies opts on top of the default styles.
+func newMermaidOptions(opts []MermaidOption) *MermaidOptions {
+	options := &MermaidOptions{Styles: DefaultStyles()}
+	for _, opt := range opts {
+		opt(options)
 	}
+	return options
 }
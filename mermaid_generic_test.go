@@ -534,3 +534,120 @@ func TestComponentDiagram_NilConfigWithCustomState(t *testing.T) {
 		t.Error("ComponentDiagram with nil config should apply correct CSS class")
 	}
 }
+
+func TestMermaid_EscapesLabels(t *testing.T) {
+	type Node struct {
+		Name     string
+		Status   string
+		Metadata map[string]string
+	}
+	root := Node{
+		Name:     `evil"]-->x[<script>alert(1)</script>`,
+		Status:   "a\nb #quot;",
+		Metadata: map[string]string{"image": "<img src=x onerror=alert(1)>"},
+	}
+
+	diagram := TreeDiagram(root, nil)
+	for _, bad := range []string{"<script>", "<img", `"]-->`, "a\nb"} {
+		if strings.Contains(diagram, bad) {
+			t.Errorf("diagram contains unescaped %q:\n%s", bad, diagram)
+		}
+	}
+	for _, want := range []string{"#lt;script>", "#quot;]-->x[", "a<br/>b #35;quot;", "<b>"} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("diagram missing %q:\n%s", want, diagram)
+		}
+	}
+
+	config := DefaultDiagramConfig()
+	config.SecondaryLabel = "Workers [pool]"
+	config.ConnectionLabel = "--> owns"
+	diagram = ComponentDiagram(struct{}{}, root, config)
+	for _, want := range []string{`subgraph secondary_graph ["Workers [pool]"]`, `primary -- "--> owns" --> secondary`} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("diagram missing %q:\n%s", want, diagram)
+		}
+	}
+
+	smConfig := DefaultStateMachineConfig()
+	smConfig.NoteGenerator = func(any) string { return "        <b>draining</b>\n        end note\n" }
+	diagram = StateMachineDiagram(struct{ Stopping bool }{true}, smConfig)
+	if !strings.Contains(diagram, "#lt;b>draining#lt;/b>\n        #101;nd note\n    end note") {
+		t.Errorf("note not escaped:\n%s", diagram)
+	}
+
+	trusted := TreeDiagram(root, nil, WithTrustedHTML())
+	if !strings.Contains(trusted, "<script>") || strings.Contains(trusted, `"]-->`) {
+		t.Errorf("trusted HTML should keep tags but still escape quotes:\n%s", trusted)
+	}
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []ComponentEvent{
+		seqEvent{"api\nend", "<script>x</script>; p0->>p0: forged", "svc #1\nend", at},
+	}
+	diagram = SequenceDiagram(events, &SequenceConfig{HideTimestamps: true})
+	for _, want := range []string{
+		"    box transparent svc #35;1<br/>end\n",
+		"        participant p0 as api<br/>end\n",
+		"    p0->>p0: #lt;script>x#lt;/script>#59; p0->>p0: forged\n",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("sequence diagram missing %q:\n%s", want, diagram)
+		}
+	}
+	trusted = SequenceDiagram(events, &SequenceConfig{HideTimestamps: true}, WithTrustedHTML())
+	if !strings.Contains(trusted, "p0->>p0: <script>x</script>#59; p0->>p0: forged\n") {
+		t.Errorf("trusted HTML should keep tags but still escape semicolons:\n%s", trusted)
+	}
+}
+
+func TestMermaid_LayoutOptions(t *testing.T) {
+	type Node struct {
+		Name     string
+		Children []Node
+	}
+	root := Node{Name: "root", Children: []Node{{Name: "a"}, {Name: "b"}}}
+
+	if diagram := TreeDiagram(root, nil); !strings.HasPrefix(diagram, "graph TD\n") {
+		t.Errorf("default header changed:\n%s", diagram)
+	}
+
+	diagram := TreeDiagram(root, nil,
+		WithDirection(DirectionLR),
+		WithFlowchartKeyword(),
+		WithTheme("dark"),
+		WithCurve("basis"),
+		WithSpacing(20, 60),
+	)
+	want := `%%{init: {"flowchart":{"curve":"basis","nodeSpacing":20,"rankSpacing":60},"theme":"dark"}}%%` + "\nflowchart LR\n"
+	if !strings.HasPrefix(diagram, want) {
+		t.Errorf("diagram should start with %q:\n%s", want, diagram)
+	}
+
+	diagram = ComponentDiagram(struct{}{}, root, nil, WithSubgraphDirection("secondary", DirectionLR))
+	if !strings.Contains(diagram, "    subgraph secondary_graph [Secondary Component]\n        direction LR\n") {
+		t.Errorf("subgraph direction missing:\n%s", diagram)
+	}
+	if strings.Contains(diagram, "subgraph primary_graph [Primary Component]\n        direction") {
+		t.Errorf("direction should only apply to the named subgraph:\n%s", diagram)
+	}
+
+	m := &StateMachine{
+		States: []State{
+			{Name: "Running"},
+			{Name: "Restarting"},
+			{Name: "Backoff", Parent: "Restarting", Initial: true},
+		},
+		Transitions: []Transition{{From: "Running", To: "Restarting"}},
+	}
+	diagram = m.Diagram("Running", WithDirection(DirectionLR), WithSubgraphDirection("Restarting", DirectionTB))
+	for _, want := range []string{"stateDiagram-v2\n    direction LR\n", "state Restarting {\n        direction TB\n"} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("state diagram missing %q:\n%s", want, diagram)
+		}
+	}
+
+	if legacy := WorkerTreeDiagram(root, WithDirection(DirectionRL)); !strings.HasPrefix(legacy, "graph RL\n") {
+		t.Errorf("legacy diagram should honor the direction:\n%s", legacy)
+	}
+}
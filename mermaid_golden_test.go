@@ -0,0 +1,65 @@
+package introspection
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// golden compares got with testdata/<name>.golden. The Mermaid golden files hold the
+// output of the original string-building generators, so default diagrams must not change.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s (run with -update to regenerate)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func goldenTree() graphTestNode {
+	return graphTestNode{
+		Name:     "root",
+		Status:   "Running",
+		Metadata: map[string]string{"type": "supervisor"},
+		Children: []graphTestNode{
+			{Name: "a", Status: "Failed", PID: 10, Metadata: map[string]string{"type": "process", "restarts": "2"}},
+			{Name: "b", Children: []graphTestNode{{Name: "c", Status: "Stopped", Metadata: map[string]string{"type": "goroutine"}}}},
+		},
+	}
+}
+
+func TestTreeDiagram_Golden(t *testing.T) {
+	golden(t, "tree", []byte(TreeDiagram(goldenTree(), nil)))
+}
+
+func TestComponentDiagram_Golden(t *testing.T) {
+	primary := struct {
+		Enabled  bool
+		Stopping bool
+		Reason   string
+	}{Enabled: true, Reason: "ok"}
+	golden(t, "component", []byte(ComponentDiagram(primary, goldenTree(), nil)))
+}
+
+func TestStateMachineDiagram_Golden(t *testing.T) {
+	state := struct {
+		ForceExitThreshold int
+		Stopping           bool
+	}{ForceExitThreshold: 3, Stopping: true}
+	config := DefaultStateMachineConfig()
+	config.NoteGenerator = func(any) string { return "        Timeout: 5s\n" }
+	golden(t, "statemachine", []byte(StateMachineDiagram(state, config)))
+}
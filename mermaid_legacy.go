@@ -17,7 +17,7 @@ func SystemDiagram(sig, work any, opts ...MermaidOption) string {
 
 	var sb strings.Builder
 
-	sb.WriteString("graph TD\n")
+	options.writeFlowchartHeader(&sb)
 
 	// 1. Signal Context Subgraph
 	sb.WriteString("    subgraph ControlPlane [Signal Context]\n")
@@ -58,7 +58,9 @@ func SignalStateMachine(sig any, opts ...MermaidOption) string {
 	stopping := getBoolField(v, "Stopping")
 	received := getField(v, "Received")
 
+	options.writeInit(&sb)
 	sb.WriteString("stateDiagram-v2\n")
+	writeDirection(&sb, options.Direction, "    ")
 	sb.WriteString("    [*] --> Running\n")
 
 	signals := "SIGTERM"
@@ -122,7 +124,7 @@ func WorkerTreeDiagram(s any, opts ...MermaidOption) string {
 	}
 
 	var sb strings.Builder
-	sb.WriteString("graph TD\n")
+	options.writeFlowchartHeader(&sb)
 	sb.WriteString(options.Styles)
 	renderWorkerNode(&sb, s, "root", "    ")
 	return sb.String()
@@ -0,0 +1,304 @@
+package introspection
+
+import (
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRateWindow is the window over which Metrics computes transition rates.
+const DefaultRateWindow = time.Minute
+
+// DefaultDwellBuckets are the default upper bounds, in seconds, of dwell-time histograms.
+var DefaultDwellBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// StateTransition identifies a transition between two named states.
+type StateTransition struct {
+	From string
+	To   string
+}
+
+// Histogram is a cumulative histogram of durations in seconds.
+type Histogram struct {
+	Buckets []float64 // Upper bounds in seconds, ascending
+	Counts  []uint64  // Counts[i] is the number of observations <= Buckets[i]
+	Count   uint64    // Total observations
+	Sum     float64   // Sum of observations in seconds
+}
+
+// ComponentMetrics is a point-in-time view of the metrics of one component.
+type ComponentMetrics struct {
+	ComponentType string
+	ComponentID   string
+
+	State      string    // Current state name
+	StateSince time.Time // When the current state was entered (or first observed)
+
+	TimeInState     map[string]time.Duration   // Total time per state, including the ongoing stay
+	Transitions     map[StateTransition]uint64 // Count per observed transition
+	TransitionCount uint64                     // Total transitions
+	LastTransition  time.Time                  // Zero if the component never changed state
+	Rate            float64                    // Transitions per second over the rate window
+	Dwell           map[string]Histogram       // Durations of completed stays per state
+}
+
+// MetricsOption configures a Metrics collector.
+type MetricsOption func(*Metrics)
+
+// WithRateWindow sets the rolling window used for transition rates (default: DefaultRateWindow).
+func WithRateWindow(d time.Duration) MetricsOption {
+	return func(m *Metrics) {
+		if d > 0 {
+			m.window = d
+		}
+	}
+}
+
+// WithDwellBuckets sets the upper bounds, in seconds, of dwell-time histograms
+// (default: DefaultDwellBuckets). Duplicate, NaN and infinite bounds are dropped;
+// observations above the last bound are still counted in Histogram.Count.
+func WithDwellBuckets(buckets ...float64) MetricsOption {
+	return func(m *Metrics) {
+		finite := slices.DeleteFunc(slices.Clone(buckets), func(b float64) bool {
+			return math.IsNaN(b) || math.IsInf(b, 0)
+		})
+		if len(finite) > 0 {
+			slices.Sort(finite)
+			m.buckets = slices.Compact(finite)
+		}
+	}
+}
+
+// Metrics collects state durations and transition counts per component.
+// States are identified by name, as extracted from watcher states or snapshot payloads,
+// and components by ComponentType/ComponentID. It is safe for concurrent use:
+//
+//	metrics := NewMetrics()
+//	go ObserveWatcher(ctx, metrics, "worker", "w1", worker, func(s WorkerState) string { return s.Status })
+//	go metrics.Consume(ctx, agg.Snapshots(ctx), statusOf)
+//
+//	m, _ := metrics.Component("worker", "w1")
+//	fmt.Println(m.State, m.TimeInState[m.State], m.TransitionCount)
+type Metrics struct {
+	window  time.Duration
+	buckets []float64
+
+	mu         sync.Mutex
+	components map[ComponentKey]*componentMetrics
+}
+
+type componentMetrics struct {
+	state       string
+	since       time.Time
+	timeInState map[string]time.Duration // Completed stays only
+	transitions map[StateTransition]uint64
+	total       uint64
+	last        time.Time
+	recent      []time.Time // Transition times within the rate window
+	dwell       map[string]*dwellHistogram
+}
+
+type dwellHistogram struct {
+	counts []uint64 // Per bucket, not cumulative; the last entry is the +Inf bucket
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics creates an empty Metrics collector.
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	m := &Metrics{
+		window:     DefaultRateWindow,
+		buckets:    DefaultDwellBuckets,
+		components: make(map[ComponentKey]*componentMetrics),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Observe records that a component is in the named state at the given time.
+// The first observation of a component sets its initial state; later observations
+// with a different state count as transitions. A zero time is treated as now.
+func (m *Metrics) Observe(componentType, componentID, state string, at time.Time) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.components[key]
+	if !ok {
+		m.components[key] = &componentMetrics{
+			state:       state,
+			since:       at,
+			timeInState: make(map[string]time.Duration),
+			transitions: make(map[StateTransition]uint64),
+			dwell:       make(map[string]*dwellHistogram),
+		}
+		return
+	}
+	if c.state == state {
+		return
+	}
+
+	dwell := max(at.Sub(c.since), 0)
+	c.timeInState[c.state] += dwell
+	m.observeDwell(c, dwell)
+	c.transitions[StateTransition{From: c.state, To: state}]++
+	c.total++
+	c.last = at
+	c.recent = append(c.recent, at)
+	c.prune(at.Add(-m.window))
+
+	c.state = state
+	c.since = at
+}
+
+// observeDwell adds a completed stay in the current state of c. The caller must hold m.mu.
+func (m *Metrics) observeDwell(c *componentMetrics, dwell time.Duration) {
+	h, ok := c.dwell[c.state]
+	if !ok {
+		h = &dwellHistogram{counts: make([]uint64, len(m.buckets)+1)}
+		c.dwell[c.state] = h
+	}
+	seconds := dwell.Seconds()
+	i, _ := slices.BinarySearch(m.buckets, seconds)
+	h.counts[i]++
+	h.count++
+	h.sum += seconds
+}
+
+// Delete forgets a component. It reports whether the component was present.
+func (m *Metrics) Delete(componentType, componentID string) bool {
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.components[key]; !ok {
+		return false
+	}
+	delete(m.components, key)
+	return true
+}
+
+// Consume observes every snapshot received from in until it is closed or ctx is cancelled.
+// stateName extracts the state name from a snapshot; snapshots mapped to "" are ignored.
+func (m *Metrics) Consume(ctx context.Context, in <-chan StateSnapshot, stateName func(StateSnapshot) string) {
+	for {
+		select {
+		case snapshot, ok := <-in:
+			if !ok {
+				return
+			}
+			if state := stateName(snapshot); state != "" {
+				m.Observe(snapshot.ComponentType, snapshot.ComponentID, state, snapshot.Timestamp)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ObserveWatcher records the current state of w, then every state change, under the given
+// component type and ID until ctx is cancelled or the watch channel closes.
+// It blocks, so it is usually run in its own goroutine.
+func ObserveWatcher[S any](ctx context.Context, m *Metrics, componentType, componentID string, w TypedWatcher[S], stateName func(S) string) {
+	changes := w.Watch(ctx)
+	m.Observe(componentType, componentID, stateName(w.State()), time.Now())
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			m.Observe(componentType, componentID, stateName(change.NewState), change.Timestamp)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Component returns the metrics of a single component.
+func (m *Metrics) Component(componentType, componentID string) (ComponentMetrics, bool) {
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.components[key]
+	if !ok {
+		return ComponentMetrics{}, false
+	}
+	return m.view(key, c, now), true
+}
+
+// Components returns the metrics of every component, sorted by type then ID.
+func (m *Metrics) Components() []ComponentMetrics {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]ComponentMetrics, 0, len(m.components))
+	for key, c := range m.components {
+		list = append(list, m.view(key, c, now))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ComponentType != list[j].ComponentType {
+			return list[i].ComponentType < list[j].ComponentType
+		}
+		return list[i].ComponentID < list[j].ComponentID
+	})
+	return list
+}
+
+// view builds the exported view of c at time now. The caller must hold m.mu.
+func (m *Metrics) view(key ComponentKey, c *componentMetrics, now time.Time) ComponentMetrics {
+	c.prune(now.Add(-m.window))
+
+	timeInState := maps.Clone(c.timeInState)
+	timeInState[c.state] += max(now.Sub(c.since), 0)
+
+	dwell := make(map[string]Histogram, len(c.dwell))
+	for state, h := range c.dwell {
+		counts := make([]uint64, len(m.buckets))
+		var cumulative uint64
+		for i := range counts {
+			cumulative += h.counts[i]
+			counts[i] = cumulative
+		}
+		dwell[state] = Histogram{Buckets: slices.Clone(m.buckets), Counts: counts, Count: h.count, Sum: h.sum}
+	}
+
+	return ComponentMetrics{
+		ComponentType:   key.ComponentType,
+		ComponentID:     key.ComponentID,
+		State:           c.state,
+		StateSince:      c.since,
+		TimeInState:     timeInState,
+		Transitions:     maps.Clone(c.transitions),
+		TransitionCount: c.total,
+		LastTransition:  c.last,
+		Rate:            float64(len(c.recent)) / m.window.Seconds(),
+		Dwell:           dwell,
+	}
+}
+
+// prune drops transition times before cutoff.
+func (c *componentMetrics) prune(cutoff time.Time) {
+	i := 0
+	for i < len(c.recent) && c.recent[i].Before(cutoff) {
+		i++
+	}
+	c.recent = c.recent[i:]
+}
@@ -0,0 +1,205 @@
+package introspection
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetrics_Observe(t *testing.T) {
+	m := NewMetrics(WithRateWindow(5 * time.Second))
+	base := time.Now().Add(-10 * time.Second)
+
+	m.Observe("task", "t1", "Pending", base)
+	m.Observe("task", "t1", "Running", base.Add(1*time.Second))
+	m.Observe("task", "t1", "Running", base.Add(2*time.Second)) // no change
+	m.Observe("task", "t1", "Failed", base.Add(7*time.Second))
+	m.Observe("task", "t1", "Running", base.Add(8*time.Second))
+
+	got, ok := m.Component("task", "t1")
+	if !ok {
+		t.Fatal("Component() not found")
+	}
+
+	if got.State != "Running" || !got.StateSince.Equal(base.Add(8*time.Second)) {
+		t.Errorf("State = %q since %s", got.State, got.StateSince)
+	}
+	if got.TransitionCount != 3 {
+		t.Errorf("TransitionCount = %d, want 3", got.TransitionCount)
+	}
+	if !got.LastTransition.Equal(base.Add(8 * time.Second)) {
+		t.Errorf("LastTransition = %s", got.LastTransition)
+	}
+	wantTransitions := map[StateTransition]uint64{
+		{From: "Pending", To: "Running"}: 1,
+		{From: "Running", To: "Failed"}:  1,
+		{From: "Failed", To: "Running"}:  1,
+	}
+	for k, want := range wantTransitions {
+		if got.Transitions[k] != want {
+			t.Errorf("Transitions[%v] = %d, want %d", k, got.Transitions[k], want)
+		}
+	}
+
+	if got.TimeInState["Pending"] != time.Second || got.TimeInState["Failed"] != time.Second {
+		t.Errorf("TimeInState = %v", got.TimeInState)
+	}
+	// Running: 6s completed plus the ongoing stay of ~2s.
+	if running := got.TimeInState["Running"]; running < 8*time.Second || running > 9*time.Second {
+		t.Errorf("TimeInState[Running] = %s, want ~8s", running)
+	}
+
+	// Only the last two transitions fall within the 5s window.
+	if want := 2.0 / 5; got.Rate != want {
+		t.Errorf("Rate = %v, want %v", got.Rate, want)
+	}
+
+	running := got.Dwell["Running"]
+	if running.Count != 1 || running.Sum != 6 {
+		t.Errorf("Dwell[Running] count/sum = %d/%v, want 1/6", running.Count, running.Sum)
+	}
+	for i, le := range running.Buckets {
+		want := uint64(0)
+		if le >= 6 {
+			want = 1
+		}
+		if running.Counts[i] != want {
+			t.Errorf("Dwell[Running] bucket le=%v = %d, want %d", le, running.Counts[i], want)
+		}
+	}
+	if pending := got.Dwell["Pending"]; pending.Counts[4] != 1 { // le=1 is inclusive
+		t.Errorf("Dwell[Pending] = %+v", pending)
+	}
+
+	// Returned maps are copies.
+	got.Transitions[StateTransition{From: "x", To: "y"}] = 1
+	again, _ := m.Component("task", "t1")
+	if len(again.Transitions) != 3 {
+		t.Error("Component() should return copies of internal maps")
+	}
+}
+
+func TestMetrics_DwellBuckets(t *testing.T) {
+	m := NewMetrics(WithDwellBuckets(10, 1))
+	base := time.Now()
+	m.Observe("c", "1", "A", base)
+	m.Observe("c", "1", "B", base.Add(500*time.Millisecond))
+	m.Observe("c", "1", "A", base.Add(60*time.Second))
+
+	got, _ := m.Component("c", "1")
+	if a := got.Dwell["A"]; len(a.Buckets) != 2 || a.Buckets[0] != 1 || a.Counts[0] != 1 || a.Counts[1] != 1 {
+		t.Errorf("Dwell[A] = %+v", a)
+	}
+	// 59.5s exceeds every bucket and only shows up in Count.
+	if b := got.Dwell["B"]; b.Counts[1] != 0 || b.Count != 1 {
+		t.Errorf("Dwell[B] = %+v", b)
+	}
+}
+
+func TestMetrics_DwellBucketsCleaned(t *testing.T) {
+	m := NewMetrics(WithDwellBuckets(5, 1, 5, math.NaN(), math.Inf(1), math.Inf(-1), 1))
+	base := time.Now()
+	m.Observe("c", "1", "A", base)
+	m.Observe("c", "1", "B", base.Add(2*time.Second))
+
+	got, _ := m.Component("c", "1")
+	a := got.Dwell["A"]
+	if len(a.Buckets) != 2 || a.Buckets[0] != 1 || a.Buckets[1] != 5 || a.Counts[0] != 0 || a.Counts[1] != 1 {
+		t.Errorf("Dwell[A] = %+v, want buckets [1 5]", a)
+	}
+
+	// Only unusable bounds leave the defaults in place.
+	m = NewMetrics(WithDwellBuckets(math.NaN(), math.Inf(1)))
+	m.Observe("c", "1", "A", base)
+	m.Observe("c", "1", "B", base.Add(time.Second))
+	got, _ = m.Component("c", "1")
+	if a := got.Dwell["A"]; len(a.Buckets) != len(DefaultDwellBuckets) {
+		t.Errorf("Dwell[A].Buckets = %v, want defaults", a.Buckets)
+	}
+}
+
+func TestMetrics_ComponentsAndDelete(t *testing.T) {
+	m := NewMetrics()
+	m.Observe("worker", "b", "Idle", time.Time{})
+	m.Observe("worker", "a", "Idle", time.Time{})
+	m.Observe("manager", "z", "Running", time.Time{})
+
+	list := m.Components()
+	var keys []string
+	for _, c := range list {
+		keys = append(keys, c.ComponentType+"/"+c.ComponentID)
+	}
+	if want := []string{"manager/z", "worker/a", "worker/b"}; len(keys) != 3 || keys[0] != want[0] || keys[1] != want[1] || keys[2] != want[2] {
+		t.Errorf("Components() = %v, want %v", keys, want)
+	}
+
+	if !m.Delete("worker", "a") {
+		t.Error("Delete() = false for present component")
+	}
+	if m.Delete("worker", "a") {
+		t.Error("Delete() = true for absent component")
+	}
+	if _, ok := m.Component("worker", "a"); ok {
+		t.Error("deleted component still present")
+	}
+}
+
+func TestMetrics_Sources(t *testing.T) {
+	type phase struct{ Name string }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMetrics()
+	b := NewBroadcaster("task", "t1", phase{"Pending"})
+	defer b.Close()
+
+	snapshots := make(chan StateSnapshot)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ObserveWatcher(ctx, m, "task", "t1", TypedWatcher[phase](b), func(p phase) string { return p.Name })
+	}()
+	go func() {
+		defer wg.Done()
+		m.Consume(ctx, snapshots, func(s StateSnapshot) string {
+			name, _ := s.Payload.(string)
+			return name
+		})
+	}()
+
+	snapshots <- StateSnapshot{ComponentType: "svc", ComponentID: "s1", Payload: "Up"}
+	snapshots <- StateSnapshot{ComponentType: "svc", ComponentID: "s1", Payload: 42} // ignored
+	snapshots <- StateSnapshot{ComponentType: "svc", ComponentID: "s1", Payload: "Down"}
+	close(snapshots)
+
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		deadline := time.After(time.Second)
+		for !cond() {
+			select {
+			case <-deadline:
+				t.Fatalf("timed out; metrics = %+v", m.Components())
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}
+
+	// The watcher subscribes before recording its initial state.
+	waitFor(func() bool { _, ok := m.Component("task", "t1"); return ok })
+	b.Publish(phase{"Pending"}, phase{"Running"})
+
+	waitFor(func() bool { c, _ := m.Component("task", "t1"); return c.State == "Running" })
+	waitFor(func() bool { c, _ := m.Component("svc", "s1"); return c.State == "Down" })
+
+	if c, _ := m.Component("svc", "s1"); c.TransitionCount != 1 {
+		t.Errorf("svc TransitionCount = %d, want 1", c.TransitionCount)
+	}
+
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,259 @@
+package introspection
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultDwellSamples is the number of most recent dwell times kept per transition
+// for percentile estimates.
+const DefaultDwellSamples = 1024
+
+// ObservedState summarizes a state seen by a TransitionRecorder.
+type ObservedState struct {
+	Name      string
+	Visits    int // Times the state was entered (or first observed)
+	FirstSeen time.Time
+	LastSeen  time.Time
+	MeanDwell time.Duration // Mean time spent in the state before leaving it; zero if never left
+	Initial   bool          // The state was the first one observed for some component
+}
+
+// ObservedTransition summarizes a transition seen by a TransitionRecorder.
+type ObservedTransition struct {
+	From      string
+	To        string
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	P50Dwell  time.Duration // Median time spent in From before taking this transition; zero if unknown
+}
+
+// TransitionRecorder infers the actually-observed state machine from StateChange values.
+// A key function maps each state S to a state name; changes whose old and new names
+// are equal are ignored. Dwell times are measured per component (ComponentType/ComponentID),
+// so a single recorder can observe many components of the same kind.
+// It is safe for concurrent use.
+type TransitionRecorder[S any] struct {
+	key func(S) string
+
+	mu          sync.Mutex
+	states      map[string]*observedState
+	stateOrder  []string
+	transitions map[[2]string]*observedTransition
+	transOrder  [][2]string
+	components  map[ComponentKey]*observedComponent
+	last        ComponentKey // The component that changed most recently
+}
+
+// observedComponent tracks the state of a single component.
+type observedComponent struct {
+	current string
+	entered time.Time // When current was entered; zero until the first transition
+}
+
+type observedState struct {
+	ObservedState
+	dwellTotal time.Duration
+	dwellCount int
+}
+
+type observedTransition struct {
+	ObservedTransition
+	samples []time.Duration
+	next    int
+}
+
+// NewTransitionRecorder creates a recorder that names states with key.
+func NewTransitionRecorder[S any](key func(S) string) *TransitionRecorder[S] {
+	return &TransitionRecorder[S]{
+		key:         key,
+		states:      make(map[string]*observedState),
+		transitions: make(map[[2]string]*observedTransition),
+		components:  make(map[ComponentKey]*observedComponent),
+	}
+}
+
+// Record adds a single state change. A zero Timestamp is treated as now.
+func (r *TransitionRecorder[S]) Record(change StateChange[S]) {
+	from, to := r.key(change.OldState), r.key(change.NewState)
+	at := change.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	component := ComponentKey{ComponentType: change.ComponentType, ComponentID: change.ComponentID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, known := r.components[component]
+	if !known {
+		// First change for this component: its old state is where it started,
+		// but when it entered that state is unknown.
+		s := r.state(from, at)
+		s.Initial = true
+		s.Visits++
+		c = &observedComponent{current: from}
+		r.components[component] = c
+	}
+	r.last = component
+	if from == to {
+		return
+	}
+
+	src := r.state(from, at)
+	src.LastSeen = at
+	dst := r.state(to, at)
+	dst.Visits++
+	dst.LastSeen = at
+
+	t := r.transition(from, to, at)
+	t.Count++
+	t.LastSeen = at
+
+	enteredAt := c.entered
+	c.current, c.entered = to, at
+	if !enteredAt.IsZero() {
+		dwell := at.Sub(enteredAt)
+		src.dwellTotal += dwell
+		src.dwellCount++
+		if len(t.samples) < DefaultDwellSamples {
+			t.samples = append(t.samples, dwell)
+		} else {
+			t.samples[t.next] = dwell
+			t.next = (t.next + 1) % DefaultDwellSamples
+		}
+	}
+}
+
+// Consume records every change from in until in is closed or ctx is cancelled.
+func (r *TransitionRecorder[S]) Consume(ctx context.Context, in <-chan StateChange[S]) {
+	for {
+		select {
+		case change, ok := <-in:
+			if !ok {
+				return
+			}
+			r.Record(change)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// States returns the observed states in order of first appearance.
+func (r *TransitionRecorder[S]) States() []ObservedState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make([]ObservedState, 0, len(r.stateOrder))
+	for _, name := range r.stateOrder {
+		s := r.states[name]
+		out := s.ObservedState
+		if s.dwellCount > 0 {
+			out.MeanDwell = s.dwellTotal / time.Duration(s.dwellCount)
+		}
+		states = append(states, out)
+	}
+	return states
+}
+
+// Transitions returns the observed transitions in order of first appearance.
+func (r *TransitionRecorder[S]) Transitions() []ObservedTransition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transitions := make([]ObservedTransition, 0, len(r.transOrder))
+	for _, k := range r.transOrder {
+		t := r.transitions[k]
+		out := t.ObservedTransition
+		if len(t.samples) > 0 {
+			sorted := slices.Clone(t.samples)
+			slices.Sort(sorted)
+			out.P50Dwell = sorted[(len(sorted)-1)/2]
+		}
+		transitions = append(transitions, out)
+	}
+	return transitions
+}
+
+// Current returns the current state of the component that changed most recently,
+// or "" if no change was recorded.
+func (r *TransitionRecorder[S]) Current() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.components[r.last]; ok {
+		return c.current
+	}
+	return ""
+}
+
+// CurrentOf returns the current state of a component, or "" if no change was recorded for it.
+func (r *TransitionRecorder[S]) CurrentOf(component ComponentKey) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.components[component]; ok {
+		return c.current
+	}
+	return ""
+}
+
+// StateMachine returns the observed transitions as a StateMachine.
+// Transitions are labeled with their count and median dwell time (e.g. "x42, p50 120ms")
+// and states with their mean dwell time.
+func (r *TransitionRecorder[S]) StateMachine() *StateMachine {
+	m := &StateMachine{}
+	for _, s := range r.States() {
+		state := State{Name: s.Name, Initial: s.Initial}
+		if s.MeanDwell > 0 {
+			state.Label = fmt.Sprintf("%s (mean %s)", s.Name, formatDwell(s.MeanDwell))
+		}
+		m.States = append(m.States, state)
+	}
+	for _, t := range r.Transitions() {
+		label := fmt.Sprintf("x%d", t.Count)
+		if t.P50Dwell > 0 {
+			label += ", p50 " + formatDwell(t.P50Dwell)
+		}
+		m.Transitions = append(m.Transitions, Transition{From: t.From, To: t.To, Event: label})
+	}
+	return m
+}
+
+// Diagram renders the observed state machine as a Mermaid state diagram,
+// highlighting the current state of the component that changed most recently.
+func (r *TransitionRecorder[S]) Diagram(opts ...MermaidOption) string {
+	return r.StateMachine().Diagram(r.Current(), opts...)
+}
+
+func (r *TransitionRecorder[S]) state(name string, at time.Time) *observedState {
+	s, ok := r.states[name]
+	if !ok {
+		s = &observedState{ObservedState: ObservedState{Name: name, FirstSeen: at, LastSeen: at}}
+		r.states[name] = s
+		r.stateOrder = append(r.stateOrder, name)
+	}
+	return s
+}
+
+func (r *TransitionRecorder[S]) transition(from, to string, at time.Time) *observedTransition {
+	k := [2]string{from, to}
+	t, ok := r.transitions[k]
+	if !ok {
+		t = &observedTransition{ObservedTransition: ObservedTransition{From: from, To: to, FirstSeen: at}}
+		r.transitions[k] = t
+		r.transOrder = append(r.transOrder, k)
+	}
+	return t
+}
+
+// formatDwell rounds d to a readable precision.
+func formatDwell(d time.Duration) string {
+	if d >= time.Millisecond {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
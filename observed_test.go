@@ -0,0 +1,155 @@
+package introspection
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransitionRecorder_Stats(t *testing.T) {
+	type phase struct{ Name string }
+	key := func(p phase) string { return p.Name }
+
+	r := NewTransitionRecorder(key)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	change := func(id, from, to string, at time.Duration) StateChange[phase] {
+		return StateChange[phase]{
+			ComponentType: "task",
+			ComponentID:   id,
+			OldState:      phase{from},
+			NewState:      phase{to},
+			Timestamp:     base.Add(at),
+		}
+	}
+
+	// Two components interleaved; dwell times are tracked per component.
+	r.Record(change("a", "Pending", "Running", 0))
+	r.Record(change("b", "Pending", "Running", 10*time.Millisecond))
+	r.Record(change("a", "Running", "Running", 50*time.Millisecond)) // same state: ignored
+	r.Record(change("a", "Running", "Failed", 100*time.Millisecond))
+	r.Record(change("b", "Running", "Failed", 310*time.Millisecond))
+	r.Record(change("a", "Failed", "Running", 400*time.Millisecond))
+	r.Record(change("a", "Running", "Failed", 520*time.Millisecond))
+
+	transitions := r.Transitions()
+	if len(transitions) != 3 {
+		t.Fatalf("len(Transitions()) = %d, want 3: %+v", len(transitions), transitions)
+	}
+
+	tests := []struct {
+		from, to string
+		count    int
+		p50      time.Duration
+		first    time.Duration
+		last     time.Duration
+	}{
+		// The first change of a component has no known entry time, so no dwell sample.
+		{"Pending", "Running", 2, 0, 0, 10 * time.Millisecond},
+		{"Running", "Failed", 3, 120 * time.Millisecond, 100 * time.Millisecond, 520 * time.Millisecond},
+		{"Failed", "Running", 1, 300 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		got := transitions[i]
+		if got.From != tt.from || got.To != tt.to || got.Count != tt.count || got.P50Dwell != tt.p50 {
+			t.Errorf("transition %d = %+v, want %s->%s x%d p50 %s", i, got, tt.from, tt.to, tt.count, tt.p50)
+		}
+		if !got.FirstSeen.Equal(base.Add(tt.first)) || !got.LastSeen.Equal(base.Add(tt.last)) {
+			t.Errorf("transition %d seen %s..%s", i, got.FirstSeen, got.LastSeen)
+		}
+	}
+
+	states := r.States()
+	byName := map[string]ObservedState{}
+	for _, s := range states {
+		byName[s.Name] = s
+	}
+	if !byName["Pending"].Initial || byName["Running"].Initial {
+		t.Errorf("initial states = %+v", states)
+	}
+	// Running dwell samples: 100ms (a), 300ms (b), 120ms (a) -> mean 173.333ms
+	if got := byName["Running"].MeanDwell.Round(time.Millisecond); got != 173*time.Millisecond {
+		t.Errorf("Running mean dwell = %s, want 173ms", got)
+	}
+	if got := byName["Running"].Visits; got != 3 {
+		t.Errorf("Running visits = %d, want 3", got)
+	}
+	if got := r.Current(); got != "Failed" {
+		t.Errorf("Current() = %q, want Failed", got)
+	}
+
+	diagram := r.Diagram()
+	for _, want := range []string{
+		"    [*] --> Pending\n",
+		"    Pending --> Running: x2\n",
+		"    Running --> Failed: x3, p50 120ms\n",
+		"    Failed --> Running: x1, p50 300ms\n",
+		"    state \"Running (mean 173ms)\" as Running\n",
+		"    class Failed active\n",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("Diagram() missing %q\n%s", want, diagram)
+		}
+	}
+
+	// A first change within the same state does not start dwell timing.
+	r.Record(change("c", "Idle", "Idle", 600*time.Millisecond))
+	r.Record(change("c", "Idle", "Running", 900*time.Millisecond))
+	for _, s := range r.States() {
+		if s.Name == "Idle" && (!s.Initial || s.MeanDwell != 0) {
+			t.Errorf("Idle = %+v, want initial without dwell", s)
+		}
+	}
+	if got := r.Transitions()[3]; got.From != "Idle" || got.P50Dwell != 0 {
+		t.Errorf("Idle transition = %+v, want no dwell", got)
+	}
+
+	// The current state is tracked per component.
+	for id, want := range map[string]string{"a": "Failed", "b": "Failed", "c": "Running", "d": ""} {
+		if got := r.CurrentOf(ComponentKey{ComponentType: "task", ComponentID: id}); got != want {
+			t.Errorf("CurrentOf(%s) = %q, want %q", id, got, want)
+		}
+	}
+	if got := r.Current(); got != "Running" {
+		t.Errorf("Current() = %q, want Running", got)
+	}
+}
+
+func TestTransitionRecorder_Consume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBroadcaster("worker", "w1", "Idle")
+	defer b.Close()
+
+	r := NewTransitionRecorder(func(s string) string { return s })
+
+	changes := b.Watch(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.Consume(ctx, changes)
+	}()
+
+	b.Publish("Idle", "Busy")
+	b.Publish("Busy", "Idle")
+
+	deadline := time.After(time.Second)
+	for len(r.Transitions()) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("Transitions() = %+v", r.Transitions())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	if got := r.Current(); got != "Idle" {
+		t.Errorf("Current() = %q, want Idle", got)
+	}
+}
@@ -0,0 +1,219 @@
+// Package openmetrics exposes introspection metrics in the OpenMetrics text format,
+// using only the standard library.
+//
+// The Handler can be scraped by Prometheus and other OpenMetrics-compatible collectors:
+//
+//	metrics := introspection.NewMetrics()
+//	go metrics.Consume(ctx, registry.Snapshots(ctx), statusOf)
+//
+//	http.Handle("/metrics", openmetrics.NewHandler(metrics))
+//
+// Every series is labeled with component_type and component_id. The exported families are
+// (with the default "introspection" namespace):
+//
+//	introspection_state                       gauge      1 for the current state, 0 for other known states
+//	introspection_state_since_seconds         gauge      Unix time the current state was entered
+//	introspection_time_in_state_seconds       gauge      total time spent per state
+//	introspection_transitions_total           counter    transitions per from/to pair
+//	introspection_last_transition_seconds     gauge      Unix time of the last transition
+//	introspection_transition_rate             gauge      transitions per second over the rate window
+//	introspection_dwell_seconds               histogram  duration of completed stays per state
+package openmetrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aretw0/introspection"
+)
+
+// ContentType is the media type of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// DefaultNamespace is the metric name prefix used by Write and NewHandler.
+const DefaultNamespace = "introspection"
+
+// Encoder writes component metrics in the OpenMetrics text format.
+type Encoder struct {
+	// Namespace prefixes every metric family name (default: DefaultNamespace).
+	Namespace string
+}
+
+// Write encodes components with the default namespace.
+func Write(w io.Writer, components []introspection.ComponentMetrics) error {
+	return Encoder{}.Encode(w, components)
+}
+
+// Encode writes every metric family for components, terminated by "# EOF".
+// Series are ordered by component (as given), then by state or transition name,
+// so the output is deterministic for a given input.
+func (e Encoder) Encode(w io.Writer, components []introspection.ComponentMetrics) error {
+	ns := e.Namespace
+	if ns == "" {
+		ns = DefaultNamespace
+	}
+
+	bw := bufio.NewWriter(w)
+	family := func(name, typ, unit, help string) {
+		fmt.Fprintf(bw, "# TYPE %s_%s %s\n", ns, name, typ)
+		if unit != "" {
+			fmt.Fprintf(bw, "# UNIT %s_%s %s\n", ns, name, unit)
+		}
+		fmt.Fprintf(bw, "# HELP %s_%s %s\n", ns, name, help)
+	}
+	sample := func(name string, labels []string, value float64) {
+		fmt.Fprintf(bw, "%s_%s{%s} %s\n", ns, name, strings.Join(labels, ","), formatValue(value))
+	}
+
+	family("state", "gauge", "", "Whether the component is in the state (1) or not (0).")
+	for _, c := range components {
+		for _, state := range sortedKeys(c.TimeInState) {
+			value := 0.0
+			if state == c.State {
+				value = 1
+			}
+			sample("state", append(componentLabels(c), label("state", state)), value)
+		}
+	}
+
+	family("state_since_seconds", "gauge", "seconds", "Unix time the current state was entered.")
+	for _, c := range components {
+		sample("state_since_seconds", append(componentLabels(c), label("state", c.State)), unixSeconds(c.StateSince))
+	}
+
+	family("time_in_state_seconds", "gauge", "seconds", "Total time spent in the state.")
+	for _, c := range components {
+		for _, state := range sortedKeys(c.TimeInState) {
+			sample("time_in_state_seconds", append(componentLabels(c), label("state", state)), c.TimeInState[state].Seconds())
+		}
+	}
+
+	family("transitions", "counter", "", "State transitions.")
+	for _, c := range components {
+		transitions := make([]introspection.StateTransition, 0, len(c.Transitions))
+		for t := range c.Transitions {
+			transitions = append(transitions, t)
+		}
+		slices.SortFunc(transitions, func(a, b introspection.StateTransition) int {
+			if a.From != b.From {
+				return strings.Compare(a.From, b.From)
+			}
+			return strings.Compare(a.To, b.To)
+		})
+		for _, t := range transitions {
+			sample("transitions_total", append(componentLabels(c), label("from", t.From), label("to", t.To)), float64(c.Transitions[t]))
+		}
+	}
+
+	family("last_transition_seconds", "gauge", "seconds", "Unix time of the last state transition.")
+	for _, c := range components {
+		if !c.LastTransition.IsZero() {
+			sample("last_transition_seconds", componentLabels(c), unixSeconds(c.LastTransition))
+		}
+	}
+
+	family("transition_rate", "gauge", "", "State transitions per second over the rate window.")
+	for _, c := range components {
+		sample("transition_rate", componentLabels(c), c.Rate)
+	}
+
+	family("dwell_seconds", "histogram", "seconds", "Duration of completed stays in the state.")
+	for _, c := range components {
+		for _, state := range sortedKeys(c.Dwell) {
+			h := c.Dwell[state]
+			labels := append(componentLabels(c), label("state", state))
+			for i, le := range h.Buckets {
+				sample("dwell_seconds_bucket", append(slices.Clone(labels), label("le", formatBound(le))), float64(h.Counts[i]))
+			}
+			sample("dwell_seconds_bucket", append(slices.Clone(labels), label("le", "+Inf")), float64(h.Count))
+			sample("dwell_seconds_count", labels, float64(h.Count))
+			sample("dwell_seconds_sum", labels, h.Sum)
+		}
+	}
+
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+// Handler serves the metrics of an introspection.Metrics collector.
+type Handler struct {
+	metrics *introspection.Metrics
+	encoder Encoder
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithNamespace sets the metric name prefix (default: DefaultNamespace).
+func WithNamespace(ns string) Option {
+	return func(h *Handler) {
+		h.encoder.Namespace = ns
+	}
+}
+
+// NewHandler creates a Handler that exports metrics on every request.
+func NewHandler(metrics *introspection.Metrics, opts ...Option) *Handler {
+	h := &Handler{metrics: metrics}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if err := h.encoder.Encode(w, h.metrics.Components()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func componentLabels(c introspection.ComponentMetrics) []string {
+	return []string{label("component_type", c.ComponentType), label("component_id", c.ComponentID)}
+}
+
+// label formats a single label pair with the value escaped.
+func label(name, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return name + `="` + value + `"`
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatBound formats a histogram bucket bound in the canonical form OpenMetrics
+// requires for the le label, which always includes a decimal point or exponent.
+func formatBound(v float64) string {
+	s := formatValue(v)
+	if math.IsInf(v, 0) || math.IsNaN(v) || strings.ContainsAny(s, ".e") {
+		return s
+	}
+	return s + ".0"
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
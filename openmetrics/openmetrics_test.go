@@ -0,0 +1,114 @@
+package openmetrics
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aretw0/introspection"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s (run with -update to regenerate)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func fixture() []introspection.ComponentMetrics {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []introspection.ComponentMetrics{
+		{
+			ComponentType: "worker",
+			ComponentID:   `w"1`,
+			State:         "Running",
+			StateSince:    base.Add(90 * time.Second),
+			TimeInState: map[string]time.Duration{
+				"Pending": 1500 * time.Millisecond,
+				"Running": 75 * time.Second,
+				"Failed":  12 * time.Second,
+			},
+			Transitions: map[introspection.StateTransition]uint64{
+				{From: "Pending", To: "Running"}: 1,
+				{From: "Running", To: "Failed"}:  2,
+				{From: "Failed", To: "Running"}:  2,
+			},
+			TransitionCount: 5,
+			LastTransition:  base.Add(90 * time.Second),
+			Rate:            0.25,
+			Dwell: map[string]introspection.Histogram{
+				"Failed":  {Buckets: []float64{1, 10}, Counts: []uint64{0, 1}, Count: 2, Sum: 12},
+				"Pending": {Buckets: []float64{1, 10}, Counts: []uint64{0, 1}, Count: 1, Sum: 1.5},
+			},
+		},
+		{
+			ComponentType: "manager",
+			ComponentID:   "root",
+			State:         "Idle",
+			StateSince:    base,
+			TimeInState:   map[string]time.Duration{"Idle": time.Minute},
+		},
+	}
+}
+
+func TestWrite_Golden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, fixture()); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "metrics", buf.Bytes())
+}
+
+func TestEncoder_Namespace(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (Encoder{Namespace: "app"}).Encode(&buf, fixture()[1:]); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "namespace", buf.Bytes())
+}
+
+func TestHandler(t *testing.T) {
+	metrics := introspection.NewMetrics()
+	metrics.Observe("worker", "w1", "Idle", time.Now().Add(-time.Second))
+	metrics.Observe("worker", "w1", "Busy", time.Now())
+
+	h := NewHandler(metrics, WithNamespace("svc"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`svc_state{component_type="worker",component_id="w1",state="Busy"} 1`,
+		`svc_state{component_type="worker",component_id="w1",state="Idle"} 0`,
+		`svc_transitions_total{component_type="worker",component_id="w1",from="Idle",to="Busy"} 1`,
+		`svc_dwell_seconds_count{component_type="worker",component_id="w1",state="Idle"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q\n%s", want, body)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("body should end with # EOF")
+	}
+}
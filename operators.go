@@ -0,0 +1,275 @@
+package introspection
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stream operators compose over any channel type, typically <-chan StateChange[S]
+// and <-chan StateSnapshot:
+//
+//	changes := worker.Watch(ctx)
+//	failed := Filter(ctx, changes, func(c StateChange[WorkerState]) bool { return c.NewState.Status == "Failed" })
+//	batches := Batch(ctx, Throttle(ctx, failed, time.Second), 10*time.Second)
+//
+// Every operator runs a single goroutine that closes its output once the input is
+// closed (after flushing any pending value) or ctx is cancelled. Outputs are unbuffered;
+// use BufferSnapshots or BufferEvents to decouple slow consumers.
+
+// Filter forwards the values for which keep returns true.
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if keep(v) && !emit(ctx, out, v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Map forwards f applied to every value, for example to extract a field of the new state.
+func Map[T, U any](ctx context.Context, in <-chan T, f func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !emit(ctx, out, f(v)) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// DistinctUntilChanged drops values equal to the previously forwarded value.
+// The first value is always forwarded. Values are compared in stream order, so
+// on a stream carrying several components, filter to one component first.
+func DistinctUntilChanged[T any](ctx context.Context, in <-chan T, equal func(a, b T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var last T
+		first := true
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !first && equal(last, v) {
+					continue
+				}
+				first, last = false, v
+				if !emit(ctx, out, v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Debounce forwards a value only once no newer value has arrived for d, so a burst
+// of changes yields its last value. A pending value is flushed when in is closed.
+// If d <= 0, every value is forwarded as it arrives.
+func Debounce[T any](ctx context.Context, in <-chan T, d time.Duration) <-chan T {
+	if d <= 0 {
+		return Map(ctx, in, identity[T])
+	}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		timer := time.NewTimer(d)
+		timer.Stop()
+		defer timer.Stop()
+
+		var pending T
+		hasPending := false
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if hasPending {
+						emit(ctx, out, pending)
+					}
+					return
+				}
+				pending, hasPending = v, true
+				timer.Reset(d)
+			case <-timer.C:
+				if hasPending {
+					hasPending = false
+					if !emit(ctx, out, pending) {
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle forwards at most one value per interval d. The first value is forwarded
+// immediately; values arriving during the interval are dropped except the latest,
+// which is forwarded when the interval ends, so the final state is never lost.
+// If d <= 0, every value is forwarded as it arrives.
+func Throttle[T any](ctx context.Context, in <-chan T, d time.Duration) <-chan T {
+	if d <= 0 {
+		return Map(ctx, in, identity[T])
+	}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		timer := time.NewTimer(d)
+		timer.Stop()
+		defer timer.Stop()
+
+		var pending T
+		hasPending, open := false, false // open: an interval is running
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if hasPending {
+						emit(ctx, out, pending)
+					}
+					return
+				}
+				if open {
+					pending, hasPending = v, true
+					continue
+				}
+				if !emit(ctx, out, v) {
+					return
+				}
+				open = true
+				timer.Reset(d)
+			case <-timer.C:
+				open = false
+				if hasPending {
+					hasPending = false
+					if !emit(ctx, out, pending) {
+						return
+					}
+					open = true
+					timer.Reset(d)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Batch collects values into slices, forwarding one non-empty slice per window.
+// The partial batch is flushed when in is closed.
+// If window <= 0, every value is forwarded as a batch of its own.
+func Batch[T any](ctx context.Context, in <-chan T, window time.Duration) <-chan []T {
+	if window <= 0 {
+		return Map(ctx, in, func(v T) []T { return []T{v} })
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+
+		var batch []T
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						emit(ctx, out, batch)
+					}
+					return
+				}
+				batch = append(batch, v)
+			case <-ticker.C:
+				if len(batch) > 0 {
+					if !emit(ctx, out, batch) {
+						return
+					}
+					batch = nil
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Merge fans in several channels into one. The output is closed once every input
+// is closed or ctx is cancelled. Order is preserved per input only.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+
+	for _, in := range ins {
+		wg.Add(1)
+		go func(in <-chan T) {
+			defer wg.Done()
+			for {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					if !emit(ctx, out, v) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(in)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+func identity[T any](v T) T { return v }
+
+// emit sends v on out, reporting false if ctx is cancelled first.
+func emit[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
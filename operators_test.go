@@ -0,0 +1,170 @@
+package introspection
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// feed returns a channel that yields values and is then closed.
+func feed[T any](values ...T) <-chan T {
+	ch := make(chan T, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+// collect drains ch, failing the test if it is not closed within a second.
+func collect[T any](t *testing.T, ch <-chan T) []T {
+	t.Helper()
+	var got []T
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-timeout:
+			t.Fatalf("channel not closed; got %v so far", got)
+		}
+	}
+}
+
+func TestFilterAndMap(t *testing.T) {
+	ctx := context.Background()
+	changes := feed(
+		StateChange[string]{ComponentID: "a", NewState: "Running"},
+		StateChange[string]{ComponentID: "b", NewState: "Failed"},
+		StateChange[string]{ComponentID: "c", NewState: "Failed"},
+	)
+
+	failed := Filter(ctx, changes, func(c StateChange[string]) bool { return c.NewState == "Failed" })
+	ids := Map(ctx, failed, func(c StateChange[string]) string { return c.ComponentID })
+
+	if got := collect(t, ids); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("got %v, want [b c]", got)
+	}
+}
+
+func TestDistinctUntilChanged(t *testing.T) {
+	ctx := context.Background()
+	snapshots := feed(
+		StateSnapshot{ComponentID: "a", Payload: "Idle"},
+		StateSnapshot{ComponentID: "a", Payload: "Idle"},
+		StateSnapshot{ComponentID: "a", Payload: "Busy"},
+		StateSnapshot{ComponentID: "a", Payload: "Idle"},
+	)
+	samePayload := func(a, b StateSnapshot) bool { return a.Payload == b.Payload }
+
+	got := Map(ctx, DistinctUntilChanged(ctx, snapshots, samePayload), func(s StateSnapshot) any { return s.Payload })
+	if want := []any{"Idle", "Busy", "Idle"}; !reflect.DeepEqual(collect(t, got), want) {
+		t.Errorf("want %v", want)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	out := Debounce(ctx, in, 30*time.Millisecond)
+
+	go func() {
+		for i := 1; i <= 3; i++ {
+			in <- i // burst
+		}
+		time.Sleep(80 * time.Millisecond)
+		in <- 4
+		close(in) // 4 is flushed on close
+	}()
+
+	if got := collect(t, out); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("got %v, want [3 4]", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	out := Throttle(ctx, in, 40*time.Millisecond)
+
+	go func() {
+		for i := 1; i <= 5; i++ {
+			in <- i
+		}
+		time.Sleep(100 * time.Millisecond)
+		in <- 6
+		close(in)
+	}()
+
+	// 1 is forwarded immediately, 5 when the interval ends, 6 starts a new interval.
+	if got := collect(t, out); !reflect.DeepEqual(got, []int{1, 5, 6}) {
+		t.Errorf("got %v, want [1 5 6]", got)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	out := Batch(ctx, in, 30*time.Millisecond)
+
+	go func() {
+		in <- 1
+		in <- 2
+		time.Sleep(70 * time.Millisecond)
+		in <- 3
+		close(in)
+	}()
+
+	if got := collect(t, out); !reflect.DeepEqual(got, [][]int{{1, 2}, {3}}) {
+		t.Errorf("got %v, want [[1 2] [3]]", got)
+	}
+}
+
+func TestOperators_NonPositiveDuration(t *testing.T) {
+	ctx := context.Background()
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		if got := collect(t, Batch(ctx, feed(1, 2, 3), d)); !reflect.DeepEqual(got, [][]int{{1}, {2}, {3}}) {
+			t.Errorf("Batch(%s) = %v, want one batch per value", d, got)
+		}
+		if got := collect(t, Debounce(ctx, feed(1, 2, 3), d)); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+			t.Errorf("Debounce(%s) = %v, want every value", d, got)
+		}
+		if got := collect(t, Throttle(ctx, feed(1, 2, 3), d)); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+			t.Errorf("Throttle(%s) = %v, want every value", d, got)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	got := collect(t, Merge(ctx, feed(1, 2), feed(3), feed[int]()))
+	if len(got) != 3 {
+		t.Errorf("got %v, want 3 values", got)
+	}
+}
+
+func TestOperators_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int) // never closed
+
+	outs := []<-chan int{
+		Filter(ctx, in, func(int) bool { return true }),
+		Map(ctx, in, func(v int) int { return v }),
+		DistinctUntilChanged(ctx, in, func(a, b int) bool { return a == b }),
+		Debounce(ctx, in, time.Millisecond),
+		Throttle(ctx, in, time.Millisecond),
+		Merge(ctx, in, in),
+	}
+	batches := Batch(ctx, in, time.Millisecond)
+
+	cancel()
+	for _, out := range outs {
+		collect(t, out)
+	}
+	collect(t, batches)
+}
@@ -0,0 +1,268 @@
+package introspection
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PlantUML rendering of the generic diagrams.
+// Like the DOT functions, these build the same Graph as their Mermaid
+// counterparts. Mermaid classes are mapped to PlantUML stereotypes, and the
+// classDef lines (DefaultStyles or WithStyles) become skinparam entries for
+// those stereotypes, so a single configuration styles all three outputs.
+
+// TreePlantUML returns a PlantUML diagram representing a hierarchical tree structure.
+// It is the PlantUML equivalent of TreeDiagram.
+func TreePlantUML(root any, config *DiagramConfig, opts ...MermaidOption) string {
+	return PlantUMLRenderer{}.Render(BuildTreeGraph(root, config, opts...))
+}
+
+// ComponentPlantUML renders a topology of two components as a PlantUML diagram.
+// The primary and secondary subgraphs become nested rectangles.
+func ComponentPlantUML(primary, secondary any, config *DiagramConfig, opts ...MermaidOption) string {
+	return PlantUMLRenderer{}.Render(BuildComponentGraph(primary, secondary, config, opts...))
+}
+
+// StateMachinePlantUML renders the state machine of StateMachineDiagram as a PlantUML state diagram.
+func StateMachinePlantUML(state any, config *StateMachineConfig, opts ...MermaidOption) string {
+	return PlantUMLRenderer{}.Render(BuildStateMachineGraph(state, config, opts...))
+}
+
+// PlantUMLRenderer renders a Graph as PlantUML source.
+// Flowchart graphs become component-style diagrams; state graphs become state diagrams.
+type PlantUMLRenderer struct{}
+
+// Render implements Renderer.
+func (r PlantUMLRenderer) Render(g *Graph) string {
+	classes := parseClassDefs(g.Styles)
+	skin := map[string][]string{} // element -> skinparam entries
+	seen := map[string]bool{}
+	var elements []string
+
+	// stereotype registers the merged style of a node's classes under a single
+	// stereotype and returns its name.
+	stereotype := func(element string, n *Node) string {
+		if len(n.Classes) == 0 || isPseudoState(n.ID) {
+			return ""
+		}
+		safe := make([]string, len(n.Classes))
+		for i, class := range n.Classes {
+			safe[i] = mermaidClass(class)
+		}
+		name := strings.Join(safe, "_")
+		if key := element + "<<" + name + ">>"; !seen[key] {
+			seen[key] = true
+			if _, ok := skin[element]; !ok {
+				elements = append(elements, element)
+			}
+			skin[element] = append(skin[element], plantUMLSkinParams(classes, name, safe...)...)
+		}
+		return name
+	}
+
+	var body strings.Builder
+	if g.Kind == StateGraph {
+		r.renderStates(&body, g, stereotype)
+	} else {
+		r.renderFlowchart(&body, g, stereotype)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("@startuml\n")
+	if g.Kind == StateGraph {
+		sb.WriteString("hide empty description\n")
+	}
+	if len(elements) > 0 {
+		sb.WriteString("hide stereotype\n")
+	}
+	for _, element := range elements {
+		if len(skin[element]) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("skinparam %s {\n", element))
+		for _, p := range skin[element] {
+			sb.WriteString("    " + p + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+	sb.WriteString(body.String())
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
+
+func (r PlantUMLRenderer) renderFlowchart(sb *strings.Builder, g *Graph, stereotype func(string, *Node) string) {
+	r.renderSubgraphs(sb, g.Subgraphs, "", stereotype)
+	r.renderFlowchartBody(sb, g.Nodes, g.Edges, "", stereotype)
+	r.renderNotes(sb, g.Notes)
+}
+
+func (r PlantUMLRenderer) renderSubgraphs(sb *strings.Builder, subgraphs []*Subgraph, indent string, stereotype func(string, *Node) string) {
+	for _, sg := range subgraphs {
+		sb.WriteString(fmt.Sprintf("%srectangle %s as %s_graph {\n", indent, plantUMLQuote(sg.Label), plantUMLID(sg.ID)))
+		r.renderSubgraphs(sb, sg.Subgraphs, indent+"    ", stereotype)
+		r.renderFlowchartBody(sb, sg.Nodes, sg.Edges, indent+"    ", stereotype)
+		sb.WriteString(indent + "}\n")
+	}
+}
+
+func (r PlantUMLRenderer) renderFlowchartBody(sb *strings.Builder, nodes []*Node, edges []*Edge, indent string, stereotype func(string, *Node) string) {
+	for _, n := range nodes {
+		element := plantUMLElement(n.ShapeStart, n.ShapeEnd)
+		sb.WriteString(fmt.Sprintf("%s%s %s%s as %s\n", indent, element, plantUMLQuote(n.Label), plantUMLStereotype(stereotype(element, n)), plantUMLID(n.ID)))
+	}
+	for _, e := range edges {
+		arrow := "-->"
+		if e.Back {
+			arrow = "..>"
+		}
+		sb.WriteString(fmt.Sprintf("%s%s %s %s%s\n", indent, plantUMLID(e.From), arrow, plantUMLID(e.To), plantUMLEdgeLabel(e.Label)))
+	}
+}
+
+func (r PlantUMLRenderer) renderStates(sb *strings.Builder, g *Graph, stereotype func(string, *Node) string) {
+	r.renderStateBody(sb, g.Nodes, g.Subgraphs, g.Edges, "", stereotype)
+	r.renderNotes(sb, g.Notes)
+}
+
+// renderStateBody declares states (subgraphs become composite states), then transitions.
+func (r PlantUMLRenderer) renderStateBody(sb *strings.Builder, nodes []*Node, subgraphs []*Subgraph, edges []*Edge, indent string, stereotype func(string, *Node) string) {
+	for _, n := range nodes {
+		if isPseudoState(n.ID) {
+			continue
+		}
+		decl := plantUMLID(n.ID)
+		if label := cmp.Or(n.Label, n.ID); label != decl {
+			decl = fmt.Sprintf("%s as %s", plantUMLQuote(label), decl)
+		}
+		sb.WriteString(fmt.Sprintf("%sstate %s%s\n", indent, decl, plantUMLStereotype(stereotype("state", n))))
+	}
+	for _, sg := range subgraphs {
+		sb.WriteString(fmt.Sprintf("%sstate %s as %s {\n", indent, plantUMLQuote(sg.Label), plantUMLID(sg.ID)))
+		r.renderStateBody(sb, sg.Nodes, sg.Subgraphs, sg.Edges, indent+"    ", stereotype)
+		sb.WriteString(indent + "}\n")
+	}
+	for _, e := range edges {
+		sb.WriteString(fmt.Sprintf("%s%s --> %s%s\n", indent, plantUMLID(e.From), plantUMLID(e.To), plantUMLEdgeLabel(e.Label)))
+	}
+}
+
+func (r PlantUMLRenderer) renderNotes(sb *strings.Builder, notes []*Note) {
+	for _, note := range notes {
+		text := plantUMLNote(note.Text)
+		sb.WriteString(fmt.Sprintf("note %s %s\n", note.Position, plantUMLID(note.Target)))
+		sb.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("end note\n")
+	}
+}
+
+// plantUMLID maps pseudo-state IDs to [*] and reduces other IDs to ASCII letters,
+// digits and underscores, as mermaidID does. Declarations, transitions, subgraphs
+// and note targets all go through it, so they keep referring to the same element.
+func plantUMLID(id string) string {
+	if isPseudoState(id) {
+		return "[*]"
+	}
+	return mermaidID(id)
+}
+
+// plantUMLNote escapes the lines of a note body that PlantUML would read as the
+// end of the note or of the diagram, using the Creole escape character.
+func plantUMLNote(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		if plantUMLNoteEnd.MatchString(trimmed) || strings.HasPrefix(trimmed, "@") {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			lines[i] = indent + "~" + strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plantUMLNoteEnd matches the lines that close a multi-line note.
+var plantUMLNoteEnd = regexp.MustCompile(`^end ?note\b`)
+
+// plantUMLElement maps Mermaid node shape delimiters to PlantUML element keywords.
+func plantUMLElement(shapeStart, shapeEnd string) string {
+	switch shapeStart + shapeEnd {
+	case "{{}}":
+		return "hexagon"
+	case "[[]]":
+		return "node"
+	case "()", "([])":
+		return "card"
+	case "(())":
+		return "circle"
+	case "[()]":
+		return "database"
+	default:
+		return "rectangle"
+	}
+}
+
+// plantUMLSkinParams converts the merged Mermaid classDef properties of classes
+// to skinparam entries for the given stereotype.
+func plantUMLSkinParams(classes map[string]map[string]string, stereotype string, names ...string) []string {
+	merged := map[string]string{}
+	for _, name := range names {
+		for k, v := range classes[name] {
+			merged[k] = v
+		}
+	}
+
+	var params []string
+	add := func(param, value string) {
+		params = append(params, fmt.Sprintf("%s<<%s>> %s", param, stereotype, value))
+	}
+	if fill, ok := merged["fill"]; ok {
+		add("BackgroundColor", fill)
+	}
+	if stroke, ok := merged["stroke"]; ok {
+		add("BorderColor", stroke)
+	}
+	if color, ok := merged["color"]; ok {
+		add("FontColor", color)
+	}
+	if width, ok := merged["stroke-width"]; ok {
+		add("BorderThickness", strings.TrimSuffix(width, "px"))
+	}
+	if dash, ok := merged["stroke-dasharray"]; ok && dash != "0" {
+		add("BorderStyle", "dashed")
+	}
+	return params
+}
+
+// plantUMLStereotype formats a stereotype suffix, or nothing for an empty name.
+func plantUMLStereotype(name string) string {
+	if name == "" {
+		return ""
+	}
+	return " <<" + name + ">>"
+}
+
+// plantUMLEdgeLabel formats an edge label suffix, or nothing for an empty label.
+func plantUMLEdgeLabel(label string) string {
+	if label == "" {
+		return ""
+	}
+	return " : " + plantUMLText(label)
+}
+
+// plantUMLQuote returns a Mermaid HTML label as a quoted PlantUML string.
+func plantUMLQuote(label string) string {
+	return `"` + plantUMLText(label) + `"`
+}
+
+// plantUMLText converts line breaks to PlantUML escapes and quotes to apostrophes.
+// Other simple HTML tags such as <b> are valid Creole and are kept.
+func plantUMLText(s string) string {
+	s = strings.ReplaceAll(s, `"`, "'")
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return htmlBreak.ReplaceAllString(s, `\n`)
+}
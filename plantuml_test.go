@@ -0,0 +1,161 @@
+package introspection
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStateMachinePlantUML(t *testing.T) {
+	type ServiceState struct {
+		ForceExitThreshold int
+		Stopping           bool
+	}
+
+	config := DefaultStateMachineConfig()
+	config.InitialState = "Active"
+	config.GracefulState = "Draining"
+	config.ForcedState = "Killed"
+	config.InitialToGraceful = "SIGTERM"
+	config.NoteGenerator = func(any) string {
+		return "        Draining tasks\n        Timeout: 5s\n"
+	}
+
+	uml := StateMachinePlantUML(ServiceState{ForceExitThreshold: 3, Stopping: true}, config)
+
+	expected := []string{
+		"@startuml\n",
+		"state Draining <<stopping>>\n",
+		"[*] --> Active\n",
+		"Active --> Draining : SIGTERM\n",
+		"Draining --> Killed : Force x3\n",
+		"Killed --> [*] : Exit\n",
+		"Draining --> [*] : Complete\n",
+		"note right of Draining\n        Draining tasks\n        Timeout: 5s\nend note\n",
+		"skinparam state {\n    BackgroundColor<<stopping>> #f8d7da\n    BorderColor<<stopping>> #f5c6cb\n    FontColor<<stopping>> #721c24\n}\n",
+		"@enduml\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(uml, want) {
+			t.Errorf("StateMachinePlantUML() missing %q\n%s", want, uml)
+		}
+	}
+	if strings.Contains(uml, "<<running>>") {
+		t.Errorf("StateMachinePlantUML() should only style the current state\n%s", uml)
+	}
+}
+
+func TestComponentPlantUML_Layout(t *testing.T) {
+	type Controller struct {
+		Enabled bool
+		Reason  string
+	}
+	type Process struct {
+		Name     string
+		Status   string
+		Metadata map[string]string
+		Children []Process
+	}
+
+	config := DefaultDiagramConfig()
+	config.PrimaryID = "ctrl"
+	config.PrimaryLabel = "Control Layer"
+	config.SecondaryID = "proc"
+	config.SecondaryLabel = "Process Layer"
+	config.ConnectionLabel = "coordinates"
+
+	secondary := Process{
+		Name:     "sup",
+		Status:   "Running",
+		Metadata: map[string]string{"type": "supervisor"},
+		Children: []Process{{Name: "db", Status: "Failed", Metadata: map[string]string{"type": "container"}}},
+	}
+
+	uml := ComponentPlantUML(Controller{Enabled: true, Reason: "deploy"}, secondary, config)
+
+	expected := []string{
+		"rectangle \"Control Layer\" as ctrl_graph {\n",
+		"    rectangle \"<b>⚡ Component</b>\\nMode: Running\\nReason: deploy\" <<signal_running>> as ctrl\n",
+		"rectangle \"Process Layer\" as proc_graph {\n",
+		"    hexagon \"<b>🧠 sup</b>\\nStatus: Running\" <<supervisor_running>> as proc\n",
+		"    node \"<b>📦 db</b>\\nStatus: Failed\" <<container_failed>> as proc_0\n",
+		"    proc --> proc_0\n",
+		"ctrl --> proc : coordinates\n",
+		"skinparam hexagon {\n",
+		"BorderThickness<<supervisor_running>> 2",
+		"BackgroundColor<<container_failed>> #f8d7da",
+		"hide stereotype\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(uml, want) {
+			t.Errorf("ComponentPlantUML() missing %q\n%s", want, uml)
+		}
+	}
+}
+
+func TestTreePlantUML_CustomStyles(t *testing.T) {
+	type Node struct {
+		Name   string
+		Status string
+	}
+
+	uml := TreePlantUML(Node{Name: `say "hi"`, Status: "Running"}, nil,
+		WithStyles("classDef process stroke-dasharray: 5 5;\nclassDef running fill:#abcdef;"))
+
+	for _, want := range []string{
+		`rectangle "<b>⚙️ say 'hi'</b>\nStatus: Running" <<process_running>> as secondary`,
+		"BackgroundColor<<process_running>> #abcdef",
+		"BorderStyle<<process_running>> dashed",
+	} {
+		if !strings.Contains(uml, want) {
+			t.Errorf("TreePlantUML() missing %q\n%s", want, uml)
+		}
+	}
+}
+
+func TestStateMachinePlantUML_EscapesIDs(t *testing.T) {
+	config := DefaultStateMachineConfig()
+	config.GracefulState = "Shutting down"
+	config.ForcedState = "Killed; hard"
+	config.NoteGenerator = func(any) string {
+		return "        Draining\n        end note\n@enduml\n"
+	}
+
+	state := struct {
+		ForceExitThreshold int
+		Stopping           bool
+	}{2, true}
+	uml := StateMachinePlantUML(state, config)
+
+	for _, want := range []string{
+		"state \"Shutting down\" as Shutting_down <<stopping>>\n",
+		"state \"Killed; hard\" as Killed__hard\n",
+		"Running --> Shutting_down : Interrupt\n",
+		"Shutting_down --> Killed__hard : Force x2\n",
+		"note right of Shutting_down\n        Draining\n        ~end note\n~@enduml\nend note\n",
+	} {
+		if !strings.Contains(uml, want) {
+			t.Errorf("StateMachinePlantUML() missing %q\n%s", want, uml)
+		}
+	}
+	if strings.Count(uml, "@enduml") != 2 {
+		t.Errorf("StateMachinePlantUML() note ended the diagram early\n%s", uml)
+	}
+}
+
+func TestTreePlantUML_SanitizesStereotypes(t *testing.T) {
+	type Node struct {
+		Name   string
+		Status string
+	}
+
+	uml := TreePlantUML(Node{Name: "svc", Status: "Run ning>> <<x"}, nil)
+
+	for _, want := range []string{
+		"BorderThickness<<process_run_ning_____x>> 1\n",
+		"<<process_run_ning_____x>> as secondary\n",
+	} {
+		if !strings.Contains(uml, want) {
+			t.Errorf("TreePlantUML() missing %q\n%s", want, uml)
+		}
+	}
+}
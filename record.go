@@ -0,0 +1,223 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateType is returned by RegisterType when a name or type is already registered.
+	ErrDuplicateType = errors.New("introspection: type already registered")
+
+	// ErrInvalidRecord is returned when a recording contains a malformed line.
+	ErrInvalidRecord = errors.New("introspection: invalid record")
+)
+
+// Record kinds in a recording.
+const (
+	RecordSnapshot = "snapshot"
+	RecordEvent    = "event"
+)
+
+// TypeRegistry maps payload types to stable names, so that recorded payloads
+// can be decoded back into their concrete Go types on replay.
+// Unregistered payloads are recorded as plain JSON and replayed as json.RawMessage
+// (snapshots) or RecordedEvent (events). So are registered events that decode without
+// a ComponentID or Timestamp, typically because their fields are unexported.
+type TypeRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+// NewTypeRegistry creates an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		byName: make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+}
+
+// RegisterType registers T under name.
+// Methods cannot have type parameters, so this is a function rather than a method.
+func RegisterType[T any](reg *TypeRegistry, name string) error {
+	t := reflect.TypeFor[T]()
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.byName[name]; ok {
+		return fmt.Errorf("name %q: %w", name, ErrDuplicateType)
+	}
+	if _, ok := reg.byType[t]; ok {
+		return fmt.Errorf("type %s: %w", t, ErrDuplicateType)
+	}
+	reg.byName[name] = t
+	reg.byType[t] = name
+	return nil
+}
+
+// nameOf returns the registered name of v's type, or "".
+func (reg *TypeRegistry) nameOf(v any) string {
+	if reg == nil || v == nil {
+		return ""
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.byType[reflect.TypeOf(v)]
+}
+
+// decode unmarshals raw into a new value of the type registered as name.
+// It reports false if name is not registered.
+func (reg *TypeRegistry) decode(name string, raw json.RawMessage) (any, bool, error) {
+	if reg == nil || name == "" {
+		return nil, false, nil
+	}
+	reg.mu.RLock()
+	t, ok := reg.byName[name]
+	reg.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	ptr := reflect.New(t)
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+			return nil, false, err
+		}
+	}
+	return ptr.Elem().Interface(), true, nil
+}
+
+// recordJSON is the wire format of a single recording line.
+type recordJSON struct {
+	Kind          string          `json:"kind"`
+	ComponentType string          `json:"componentType"`
+	ComponentID   string          `json:"componentId"`
+	Timestamp     time.Time       `json:"timestamp"`
+	EventType     string          `json:"eventType,omitempty"`
+	Type          string          `json:"type,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+}
+
+// RecordedEvent is a replayed event whose concrete type was not registered.
+type RecordedEvent struct {
+	ID      string
+	Type    string
+	At      time.Time
+	Kind    string
+	Payload json.RawMessage // The event as recorded
+}
+
+func (e RecordedEvent) ComponentID() string   { return e.ID }
+func (e RecordedEvent) ComponentType() string { return e.Type }
+func (e RecordedEvent) Timestamp() time.Time  { return e.At }
+func (e RecordedEvent) EventType() string     { return e.Kind }
+
+// Recorder appends StateSnapshot and ComponentEvent values to a newline-delimited
+// JSON recording, for later playback with a Replayer. It is safe for concurrent use.
+type Recorder struct {
+	types *TypeRegistry
+
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewRecorder creates a Recorder writing to w. types may be nil.
+func NewRecorder(w io.Writer, types *TypeRegistry) *Recorder {
+	return &Recorder{types: types, w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenRecorder opens (or creates) the recording at path in append-only mode.
+func OpenRecorder(path string, types *TypeRegistry) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(f, types), nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// RecordSnapshot appends a snapshot.
+func (r *Recorder) RecordSnapshot(s StateSnapshot) error {
+	return r.write(recordJSON{
+		Kind:          RecordSnapshot,
+		ComponentType: s.ComponentType,
+		ComponentID:   s.ComponentID,
+		Timestamp:     s.Timestamp,
+	}, s.Payload)
+}
+
+// RecordEvent appends an event. The event value itself is stored as the payload.
+func (r *Recorder) RecordEvent(e ComponentEvent) error {
+	return r.write(recordJSON{
+		Kind:          RecordEvent,
+		ComponentType: e.ComponentType(),
+		ComponentID:   e.ComponentID(),
+		Timestamp:     e.Timestamp(),
+		EventType:     e.EventType(),
+	}, e)
+}
+
+// Consume records snapshots and events until both channels are closed or ctx is cancelled.
+// Either channel may be nil. It returns the first write error, if any.
+func (r *Recorder) Consume(ctx context.Context, snapshots <-chan StateSnapshot, events <-chan ComponentEvent) error {
+	for snapshots != nil || events != nil {
+		select {
+		case s, ok := <-snapshots:
+			if !ok {
+				snapshots = nil
+				continue
+			}
+			if err := r.RecordSnapshot(s); err != nil {
+				return err
+			}
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if err := r.RecordEvent(e); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) write(rec recordJSON, payload any) error {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("%s %s/%s: %w", rec.Kind, rec.ComponentType, rec.ComponentID, err)
+		}
+		rec.Payload = raw
+		rec.Type = r.types.nameOf(payload)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(rec)
+}
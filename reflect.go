@@ -2,37 +2,156 @@ package introspection
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 )
 
 // Reflection helpers for introspecting component state via struct fields.
 // These are used by the Mermaid diagram generators to extract common fields
 // (Name, Status, PID, Metadata, Children, etc.) from arbitrary state structs.
 
-func getIntField(v reflect.Value, name string) int {
-	field := v.FieldByName(name)
+// Struct tag keys recognized in `introspect:"..."` tags.
+const (
+	fieldName     = "name"
+	fieldStatus   = "status"
+	fieldPID      = "pid"
+	fieldMetadata = "metadata"
+	fieldChildren = "children"
+	fieldEnabled  = "enabled"
+	fieldStopping = "stopping"
+	fieldStopped  = "stopped"
+	fieldReason   = "reason"
+)
+
+// defaultFieldNames are the field names used when neither a FieldMapping entry
+// nor an introspect tag designates a field.
+var defaultFieldNames = map[string]string{
+	fieldName:     "Name",
+	fieldStatus:   "Status",
+	fieldPID:      "PID",
+	fieldMetadata: "Metadata",
+	fieldChildren: "Children",
+	fieldEnabled:  "Enabled",
+	fieldStopping: "Stopping",
+	fieldStopped:  "Stopped",
+	fieldReason:   "Reason",
+}
+
+// taggedFields caches, per struct type, the field index of each introspect tag key.
+var taggedFields sync.Map // reflect.Type -> map[string][]int
+
+// mappedField returns the field of struct v that holds the logical field key.
+// An explicit field name (from FieldMapping) wins over an `introspect:"key"` tag,
+// which wins over the default field name. Fields promoted from embedded structs are
+// found too, and pointers are followed. The result is invalid if there is none.
+func mappedField(v reflect.Value, key, explicit string) reflect.Value {
+	v = indirect(v)
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	if explicit != "" {
+		return promotedField(v, explicit)
+	}
+	if index, ok := fieldTags(v.Type())[key]; ok {
+		if field, err := v.FieldByIndexErr(index); err == nil {
+			return indirect(field)
+		}
+		return reflect.Value{}
+	}
+	return promotedField(v, defaultFieldNames[key])
+}
+
+// promotedField returns the named field of struct v, including fields promoted from
+// embedded structs. Unlike FieldByName it does not panic on nil embedded pointers.
+func promotedField(v reflect.Value, name string) reflect.Value {
+	field, ok := v.Type().FieldByName(name)
+	if !ok {
+		return reflect.Value{}
+	}
+	fv, err := v.FieldByIndexErr(field.Index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return indirect(fv)
+}
+
+// indirect follows pointers and interfaces. The result is invalid for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// fieldTags returns the introspect tag keys of t, including those of embedded structs.
+// Like Go's field promotion, the shallowest tagged field wins, and a key tagged on
+// several fields at that depth is ambiguous and left out.
+func fieldTags(t reflect.Type) map[string][]int {
+	if cached, ok := taggedFields.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+	tags := make(map[string][]int)
+	ambiguous := make(map[string]bool)
+	for _, field := range reflect.VisibleFields(t) {
+		key, _, _ := strings.Cut(field.Tag.Get("introspect"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		if index, exists := tags[key]; exists && len(index) <= len(field.Index) {
+			if len(index) == len(field.Index) {
+				ambiguous[key] = true
+			}
+			continue
+		}
+		tags[key] = field.Index
+		delete(ambiguous, key)
+	}
+	for key := range ambiguous {
+		delete(tags, key)
+	}
+	taggedFields.Store(t, tags)
+	return tags
+}
+
+func intValue(field reflect.Value) int {
 	if field.IsValid() && field.CanInt() {
 		return int(field.Int())
 	}
 	return 0
 }
 
-func getBoolField(v reflect.Value, name string) bool {
-	field := v.FieldByName(name)
+func boolValue(field reflect.Value) bool {
 	if field.IsValid() && field.Kind() == reflect.Bool {
 		return field.Bool()
 	}
 	return false
 }
 
-func getStringField(v reflect.Value, name string) string {
-	field := v.FieldByName(name)
+func stringValue(field reflect.Value) string {
 	if field.IsValid() && field.Kind() == reflect.String {
 		return field.String()
 	}
 	return ""
 }
 
+func getIntField(v reflect.Value, name string) int {
+	return intValue(v.FieldByName(name))
+}
+
+func getBoolField(v reflect.Value, name string) bool {
+	return boolValue(v.FieldByName(name))
+}
+
+func getStringField(v reflect.Value, name string) string {
+	return stringValue(v.FieldByName(name))
+}
+
 func getField(v reflect.Value, name string) any {
 	field := v.FieldByName(name)
 	if field.IsValid() && field.CanInterface() {
@@ -42,7 +161,10 @@ func getField(v reflect.Value, name string) any {
 }
 
 func getMapField(v reflect.Value, name string) map[string]string {
-	field := v.FieldByName(name)
+	return mapValue(v.FieldByName(name))
+}
+
+func mapValue(field reflect.Value) map[string]string {
 	if field.IsValid() && field.Kind() == reflect.Map {
 		result := make(map[string]string)
 		iter := field.MapRange()
@@ -58,8 +180,40 @@ func getMapField(v reflect.Value, name string) map[string]string {
 }
 
 func getSliceField(v reflect.Value, name string) []any {
-	field := v.FieldByName(name)
-	if field.IsValid() && field.Kind() == reflect.Slice {
+	return sliceValue(v.FieldByName(name))
+}
+
+// childrenValue returns the elements of a slice, array or map of children.
+// Map children are returned in sorted key order, with their keys.
+func childrenValue(field reflect.Value) ([]any, []string) {
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, nil
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		children := make([]any, field.Len())
+		for i := range children {
+			children[i] = field.Index(i).Interface()
+		}
+		return children, nil
+	case reflect.Map:
+		byKey := make(map[string]any, field.Len())
+		iter := field.MapRange()
+		for iter.Next() {
+			byKey[fmt.Sprint(iter.Key().Interface())] = iter.Value().Interface()
+		}
+		keys := slices.Sorted(maps.Keys(byKey))
+		children := make([]any, len(keys))
+		for i, k := range keys {
+			children[i] = byKey[k]
+		}
+		return children, keys
+	}
+	return nil, nil
+}
+
+func sliceValue(field reflect.Value) []any {
+	if field.IsValid() && field.Kind() == reflect.Slice && field.CanInterface() {
 		result := make([]any, field.Len())
 		for i := 0; i < field.Len(); i++ {
 			result[i] = field.Index(i).Interface()
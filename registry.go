@@ -0,0 +1,239 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrDuplicateComponent is returned when a component is registered twice under the same key.
+var ErrDuplicateComponent = errors.New("introspection: component already registered")
+
+// ComponentKey identifies a single component instance.
+type ComponentKey struct {
+	ComponentType string
+	ComponentID   string
+}
+
+// String returns the key formatted as "type/id".
+func (k ComponentKey) String() string {
+	return k.ComponentType + "/" + k.ComponentID
+}
+
+// Registry tracks components that can register and unregister at runtime.
+// Live subscribers automatically start watching newly registered components,
+// and streams bound to a removed component are closed.
+//
+// Registry implements SnapshotSource, so it can be combined with other sources
+// through AggregateSnapshots or Aggregator.AddSource.
+type Registry struct {
+	mu      sync.Mutex
+	entries map[ComponentKey]*registryEntry
+	subs    map[*registrySub]struct{}
+	options *StreamOptions
+}
+
+// registryEntry is a registered component and the per-subscriber watches started on it.
+type registryEntry struct {
+	source  SnapshotSource
+	cancels map[*registrySub]context.CancelFunc
+}
+
+// registrySub is a live subscription, either to every component or to a single one.
+type registrySub struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	key    *ComponentKey // nil subscribes to every component
+	out    chan StateSnapshot
+	wg     sync.WaitGroup
+}
+
+// NewRegistry creates an empty Registry.
+// By default each subscription buffers 64 snapshots and blocks when full;
+// use StreamOptions to change the buffer size or backpressure policy.
+func NewRegistry(opts ...StreamOption) *Registry {
+	return &Registry{
+		entries: make(map[ComponentKey]*registryEntry),
+		subs:    make(map[*registrySub]struct{}),
+		options: newStreamOptions(64, opts),
+	}
+}
+
+// Register adds a snapshot source under the given component type and ID.
+// Every live subscriber starts watching the source immediately.
+func (r *Registry) Register(componentType, componentID string, src SnapshotSource) error {
+	if isNilWatcher(src) {
+		return fmt.Errorf("register %s/%s: %w", componentType, componentID, ErrNilWatcher)
+	}
+	if componentType == "" {
+		return fmt.Errorf("register %q: %w", componentID, ErrNoComponentType)
+	}
+
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.entries[key]; exists {
+		return fmt.Errorf("register %s: %w", key, ErrDuplicateComponent)
+	}
+
+	entry := &registryEntry{
+		source:  src,
+		cancels: make(map[*registrySub]context.CancelFunc),
+	}
+	r.entries[key] = entry
+
+	for sub := range r.subs {
+		if sub.key == nil || *sub.key == key {
+			r.attach(sub, entry)
+		}
+	}
+
+	return nil
+}
+
+// RegisterWatcher adds a typed watcher to the registry.
+// The component type is taken from the watcher's Component implementation.
+func RegisterWatcher[S any](r *Registry, componentID string, w TypedWatcher[S]) error {
+	if isNilWatcher(w) {
+		return fmt.Errorf("register %q: %w", componentID, ErrNilWatcher)
+	}
+
+	componentType := inferComponentType(w)
+	return r.Register(componentType, componentID, NewWatcherAdapter(componentType, w))
+}
+
+// Unregister removes a component and stops every watch started on it.
+// Subscriptions created with Watch for this component are closed.
+// It reports whether the component was registered.
+func (r *Registry) Unregister(componentType, componentID string) bool {
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[key]
+	if !ok {
+		return false
+	}
+	delete(r.entries, key)
+
+	for sub, cancel := range entry.cancels {
+		cancel()
+		if sub.key != nil {
+			sub.cancel()
+		}
+	}
+
+	return true
+}
+
+// Components returns the keys of all registered components, sorted by type and ID.
+func (r *Registry) Components() []ComponentKey {
+	r.mu.Lock()
+	keys := make([]ComponentKey, 0, len(r.entries))
+	for key := range r.entries {
+		keys = append(keys, key)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ComponentType != keys[j].ComponentType {
+			return keys[i].ComponentType < keys[j].ComponentType
+		}
+		return keys[i].ComponentID < keys[j].ComponentID
+	})
+	return keys
+}
+
+// Snapshots subscribes to every registered component, including ones registered later.
+// The channel is closed when the provided context is cancelled.
+func (r *Registry) Snapshots(ctx context.Context) <-chan StateSnapshot {
+	return r.subscribe(ctx, nil)
+}
+
+// Watch subscribes to a single component.
+// The channel is closed when the provided context is cancelled or the component is unregistered.
+// If the component is not registered, ok is false and the returned channel is already closed.
+func (r *Registry) Watch(ctx context.Context, componentType, componentID string) (snapshots <-chan StateSnapshot, ok bool) {
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+
+	r.mu.Lock()
+	_, ok = r.entries[key]
+	r.mu.Unlock()
+
+	if !ok {
+		ch := make(chan StateSnapshot)
+		close(ch)
+		return ch, false
+	}
+
+	return r.subscribe(ctx, &key), true
+}
+
+// subscribe creates a subscription and attaches it to every matching entry.
+func (r *Registry) subscribe(ctx context.Context, key *ComponentKey) <-chan StateSnapshot {
+	subCtx, cancel := context.WithCancel(ctx)
+	sub := &registrySub{
+		ctx:    subCtx,
+		cancel: cancel,
+		key:    key,
+		out:    make(chan StateSnapshot),
+	}
+
+	r.mu.Lock()
+	if key != nil {
+		if entry, ok := r.entries[*key]; ok {
+			r.attach(sub, entry)
+		} else {
+			// Unregistered between Watch's lookup and now.
+			cancel()
+		}
+	} else {
+		for _, entry := range r.entries {
+			r.attach(sub, entry)
+		}
+	}
+	r.subs[sub] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-subCtx.Done()
+
+		r.mu.Lock()
+		delete(r.subs, sub)
+		for _, entry := range r.entries {
+			delete(entry.cancels, sub)
+		}
+		r.mu.Unlock()
+
+		sub.wg.Wait()
+		close(sub.out)
+	}()
+
+	return bufferStream(subCtx, sub.out, r.options, snapshotKey)
+}
+
+// attach starts forwarding an entry's snapshots to a subscriber.
+// The caller must hold r.mu.
+func (r *Registry) attach(sub *registrySub, entry *registryEntry) {
+	ctx, cancel := context.WithCancel(sub.ctx)
+	entry.cancels[sub] = cancel
+
+	sub.wg.Add(1)
+	go func() {
+		defer sub.wg.Done()
+		defer cancel()
+
+		for snapshot := range entry.source.Snapshots(ctx) {
+			select {
+			case sub.out <- snapshot:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,141 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Register_Duplicate(t *testing.T) {
+	r := NewRegistry()
+	w := NewMockTypedWatcher(MockState{})
+
+	if err := RegisterWatcher[MockState](r, "w1", w); err != nil {
+		t.Fatalf("RegisterWatcher() error = %v", err)
+	}
+	if err := RegisterWatcher[MockState](r, "w1", w); !errors.Is(err, ErrDuplicateComponent) {
+		t.Errorf("RegisterWatcher() duplicate error = %v, want ErrDuplicateComponent", err)
+	}
+	if err := RegisterWatcher[MockState](r, "w2", nil); !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("RegisterWatcher(nil) error = %v, want ErrNilWatcher", err)
+	}
+	if err := RegisterWatcher[MockState](r, "w2", (*MockTypedWatcher[MockState])(nil)); !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("RegisterWatcher(typed nil) error = %v, want ErrNilWatcher", err)
+	}
+	if err := r.Register("worker", "w2", (*Aggregator)(nil)); !errors.Is(err, ErrNilWatcher) {
+		t.Errorf("Register(typed nil) error = %v, want ErrNilWatcher", err)
+	}
+	if err := RegisterWatcher[MockState](r, "w3", untypedWatcher{w}); !errors.Is(err, ErrNoComponentType) {
+		t.Errorf("RegisterWatcher() untyped error = %v, want ErrNoComponentType", err)
+	}
+
+	want := []ComponentKey{{ComponentType: "worker", ComponentID: "w1"}}
+	got := r.Components()
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("Components() = %v, want %v", got, want)
+	}
+}
+
+func TestRegistry_Snapshots_PicksUpNewComponents(t *testing.T) {
+	r := NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscribe before anything is registered.
+	snapshots := r.Snapshots(ctx)
+
+	w := NewMockTypedWatcher(MockState{})
+	if err := RegisterWatcher[MockState](r, "late", w); err != nil {
+		t.Fatalf("RegisterWatcher() error = %v", err)
+	}
+
+	w.SendChange(StateChange[MockState]{ComponentID: "late", NewState: MockState{Value: "hello"}})
+
+	select {
+	case snap := <-snapshots:
+		if snap.ComponentID != "late" || snap.ComponentType != "worker" {
+			t.Errorf("Unexpected snapshot %+v", snap)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for snapshot from late registration")
+	}
+}
+
+func TestRegistry_Unregister_ClosesWatch(t *testing.T) {
+	r := NewRegistry()
+	w := NewMockTypedWatcher(MockState{})
+	_ = RegisterWatcher[MockState](r, "w1", w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	single, ok := r.Watch(ctx, "worker", "w1")
+	if !ok {
+		t.Fatal("Watch() reported component as missing")
+	}
+	all := r.Snapshots(ctx)
+
+	if !r.Unregister("worker", "w1") {
+		t.Fatal("Unregister() = false, want true")
+	}
+	if r.Unregister("worker", "w1") {
+		t.Error("Second Unregister() = true, want false")
+	}
+
+	select {
+	case _, ok := <-single:
+		if ok {
+			t.Error("Watch channel should be closed after Unregister")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for Watch channel to close")
+	}
+
+	// The registry-wide subscription stays open for future components.
+	w2 := NewMockTypedWatcher(MockState{})
+	_ = RegisterWatcher[MockState](r, "w2", w2)
+	w2.SendChange(StateChange[MockState]{ComponentID: "w2"})
+
+	select {
+	case snap, ok := <-all:
+		if !ok {
+			t.Fatal("Snapshots channel closed unexpectedly")
+		}
+		if snap.ComponentID != "w2" {
+			t.Errorf("ComponentID = %q, want %q", snap.ComponentID, "w2")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for snapshot")
+	}
+}
+
+func TestRegistry_Watch_Unknown(t *testing.T) {
+	r := NewRegistry()
+
+	ch, ok := r.Watch(context.Background(), "worker", "missing")
+	if ok {
+		t.Error("Watch() ok = true for unknown component")
+	}
+	if _, open := <-ch; open {
+		t.Error("Watch() channel should be closed for unknown component")
+	}
+}
+
+func TestRegistry_Snapshots_ClosesOnCancel(t *testing.T) {
+	r := NewRegistry()
+	_ = RegisterWatcher[MockState](r, "w1", NewMockTypedWatcher(MockState{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	snapshots := r.Snapshots(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-snapshots:
+		if ok {
+			t.Error("Channel should be closed after context cancellation")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for channel close")
+	}
+}
@@ -0,0 +1,477 @@
+package introspection
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrReplayStarted is returned by Play when the recording is already being played.
+var ErrReplayStarted = errors.New("introspection: replay already started")
+
+// ReplayOption configures a Replayer.
+type ReplayOption func(*Replayer)
+
+// WithReplaySpeed sets the pacing factor relative to the recorded timeline:
+// 1 replays in real time (the default), 10 ten times faster, and 0 (or less)
+// replays every record without waiting.
+func WithReplaySpeed(factor float64) ReplayOption {
+	return func(r *Replayer) {
+		r.speed = factor
+	}
+}
+
+// WithReplayStepping makes Play wait for a call to Step before delivering each record,
+// ignoring recorded timestamps.
+func WithReplayStepping() ReplayOption {
+	return func(r *Replayer) {
+		r.stepped = true
+	}
+}
+
+// Replayer plays back a recording made by a Recorder.
+// It implements SnapshotSource and EventSource, and ReplayWatcher adapts it to
+// TypedWatcher[S], so recordings can drive the same consumers as live components.
+//
+// All streams share one timeline: subscribe with Snapshots, Events or Watch first,
+// then call Play, which delivers every record in order and closes the streams at the end:
+//
+//	replayer, err := OpenReplayer("incident.ndjson", types, WithReplaySpeed(10))
+//	worker := ReplayWatcher[WorkerState](replayer, "worker", "w1")
+//	changes := worker.Watch(ctx)
+//	go replayer.Play(ctx)
+type Replayer struct {
+	types   *TypeRegistry
+	records []recordJSON
+	speed   float64
+	stepped bool
+	step    chan struct{}
+	done    chan struct{} // Closed when Play returns
+
+	mu      sync.Mutex
+	subs    []*replaySub
+	pos     int
+	started bool
+}
+
+// replaySub is a single stream subscription. Its mutex keeps a cancelled
+// subscription from being closed while Play is delivering to it.
+type replaySub struct {
+	deliver func(ctx context.Context, rec *replayRecord) bool
+	onClose func()
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *replaySub) send(ctx context.Context, rec *replayRecord) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	return s.deliver(ctx, rec)
+}
+
+func (s *replaySub) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		if s.onClose != nil {
+			s.onClose()
+		}
+	}
+}
+
+// replayRecord is a decoded record.
+type replayRecord struct {
+	index    int
+	snapshot *StateSnapshot
+	event    ComponentEvent
+}
+
+// NewReplayer reads a whole recording from r. types may be nil, in which case
+// snapshot payloads are replayed as json.RawMessage and events as RecordedEvent.
+func NewReplayer(r io.Reader, types *TypeRegistry, opts ...ReplayOption) (*Replayer, error) {
+	rp := &Replayer{
+		types: types,
+		speed: 1,
+		step:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(rp)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec recordJSON
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w: %v", line, ErrInvalidRecord, err)
+		}
+		if rec.Kind != RecordSnapshot && rec.Kind != RecordEvent {
+			return nil, fmt.Errorf("line %d: %w: unknown kind %q", line, ErrInvalidRecord, rec.Kind)
+		}
+		rp.records = append(rp.records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// OpenReplayer reads the recording at path.
+func OpenReplayer(path string, types *TypeRegistry, opts ...ReplayOption) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayer(f, types, opts...)
+}
+
+// Len returns the number of records in the recording.
+func (r *Replayer) Len() int {
+	return len(r.records)
+}
+
+// Position returns the number of records delivered so far.
+func (r *Replayer) Position() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pos
+}
+
+// Snapshots returns a channel of the recorded snapshots, delivered as Play reaches them.
+// The channel is closed when playback ends or ctx is cancelled.
+func (r *Replayer) Snapshots(ctx context.Context) <-chan StateSnapshot {
+	out := make(chan StateSnapshot)
+	r.subscribe(ctx, &replaySub{
+		deliver: func(playCtx context.Context, rec *replayRecord) bool {
+			if rec.snapshot == nil {
+				return true
+			}
+			return send(playCtx, ctx, out, *rec.snapshot)
+		},
+		onClose: func() { close(out) },
+	})
+	return out
+}
+
+// Events returns a channel of the recorded events, delivered as Play reaches them.
+// The channel is closed when playback ends or ctx is cancelled.
+func (r *Replayer) Events(ctx context.Context) <-chan ComponentEvent {
+	out := make(chan ComponentEvent)
+	r.subscribe(ctx, &replaySub{
+		deliver: func(playCtx context.Context, rec *replayRecord) bool {
+			if rec.event == nil {
+				return true
+			}
+			return send(playCtx, ctx, out, rec.event)
+		},
+		onClose: func() { close(out) },
+	})
+	return out
+}
+
+// subscribe registers sub and removes it once ctx is cancelled.
+// Subscribing after playback has ended closes the stream immediately.
+func (r *Replayer) subscribe(ctx context.Context, sub *replaySub) {
+	if !r.addSub(sub) {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-r.done:
+			return // Play closes remaining subscriptions
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, s := range r.subs {
+			if s == sub {
+				r.subs = append(r.subs[:i], r.subs[i+1:]...)
+				sub.close()
+				return
+			}
+		}
+	}()
+}
+
+// addSub registers sub for the rest of the playback. It reports false, after
+// closing sub, if playback has already ended.
+func (r *Replayer) addSub(sub *replaySub) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.done:
+		sub.close()
+		return false
+	default:
+	}
+	r.subs = append(r.subs, sub)
+	return true
+}
+
+// Play delivers every record to the current subscribers, paced according to the
+// replay options, and closes their streams at the end. It blocks until the recording
+// is exhausted or ctx is cancelled, and may only be called once.
+func (r *Replayer) Play(ctx context.Context) error {
+	r.mu.Lock()
+	if r.started {
+		r.mu.Unlock()
+		return ErrReplayStarted
+	}
+	r.started = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		close(r.done)
+		for _, sub := range r.subs {
+			sub.close()
+		}
+		r.subs = nil
+	}()
+
+	for i, rec := range r.records {
+		if err := r.wait(ctx, i); err != nil {
+			return err
+		}
+
+		decoded, err := r.decode(rec)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		decoded.index = i
+
+		r.mu.Lock()
+		subs := append([]*replaySub(nil), r.subs...)
+		r.mu.Unlock()
+
+		for _, sub := range subs {
+			if !sub.send(ctx, decoded) && ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+
+		r.mu.Lock()
+		r.pos = i + 1
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// Step releases the next record in stepped mode. It blocks until Play accepts the step
+// and reports false once playback has ended.
+func (r *Replayer) Step() bool {
+	select {
+	case r.step <- struct{}{}:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// wait paces delivery of record i.
+func (r *Replayer) wait(ctx context.Context, i int) error {
+	if r.stepped {
+		select {
+		case <-r.step:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if i == 0 || r.speed <= 0 {
+		return ctx.Err()
+	}
+
+	gap := r.records[i].Timestamp.Sub(r.records[i-1].Timestamp)
+	delay := time.Duration(float64(gap) / r.speed)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// decode turns a wire record into a snapshot or event, using the type registry for payloads.
+func (r *Replayer) decode(rec recordJSON) (*replayRecord, error) {
+	payload, ok, err := r.types.decode(rec.Type, rec.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if rec.Kind == RecordSnapshot {
+		snapshot := StateSnapshot{
+			ComponentID:   rec.ComponentID,
+			ComponentType: rec.ComponentType,
+			Timestamp:     rec.Timestamp,
+		}
+		switch {
+		case ok:
+			snapshot.Payload = payload
+		case len(rec.Payload) > 0:
+			snapshot.Payload = rec.Payload
+		}
+		return &replayRecord{snapshot: &snapshot}, nil
+	}
+
+	// A registered event type whose fields do not survive JSON, such as one with
+	// unexported fields, decodes without its identity; the envelope still has it.
+	if event, isEvent := payload.(ComponentEvent); ok && isEvent && event.ComponentID() != "" && !event.Timestamp().IsZero() {
+		return &replayRecord{event: event}, nil
+	}
+	return &replayRecord{event: RecordedEvent{
+		ID:      rec.ComponentID,
+		Type:    rec.ComponentType,
+		At:      rec.Timestamp,
+		Kind:    rec.EventType,
+		Payload: rec.Payload,
+	}}, nil
+}
+
+// send delivers v on out unless either context is cancelled.
+func send[T any](playCtx, subCtx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-subCtx.Done():
+		return false
+	case <-playCtx.Done():
+		return false
+	}
+}
+
+// ReplayedWatcher is a TypedWatcher[S] over the recorded snapshots of one component.
+type ReplayedWatcher[S any] struct {
+	replayer      *Replayer
+	componentType string
+	componentID   string
+	initial       int // Index of the record that provides the initial state, or -1
+
+	mu    sync.RWMutex
+	state S
+}
+
+// ReplayWatcher returns a TypedWatcher[S] for one component of a recording.
+// Its State starts as the first recorded snapshot of the component and follows
+// the later snapshots delivered by Play, which Watch emits as StateChange values.
+// Payloads of type S (registered with RegisterType) are used as is; unregistered
+// payloads are decoded from their JSON form into S.
+func ReplayWatcher[S any](r *Replayer, componentType, componentID string) *ReplayedWatcher[S] {
+	w := &ReplayedWatcher[S]{replayer: r, componentType: componentType, componentID: componentID, initial: -1}
+	for i, rec := range r.records {
+		if rec.Kind != RecordSnapshot || !w.matches(rec.ComponentType, rec.ComponentID) {
+			continue
+		}
+		if decoded, err := r.decode(rec); err == nil {
+			if state, ok := payloadAs[S](decoded.snapshot.Payload); ok {
+				w.state, w.initial = state, i
+				break
+			}
+		}
+	}
+
+	r.addSub(&replaySub{
+		deliver: func(_ context.Context, rec *replayRecord) bool {
+			if state, ok := w.stateOf(rec); ok {
+				w.mu.Lock()
+				w.state = state
+				w.mu.Unlock()
+			}
+			return true
+		},
+	})
+	return w
+}
+
+// ComponentType returns the component type of the replayed component.
+func (w *ReplayedWatcher[S]) ComponentType() string {
+	return w.componentType
+}
+
+// State returns the most recently replayed state.
+func (w *ReplayedWatcher[S]) State() S {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.state
+}
+
+// Watch returns a channel of the component's replayed state changes.
+// The channel is closed when playback ends or ctx is cancelled.
+func (w *ReplayedWatcher[S]) Watch(ctx context.Context) <-chan StateChange[S] {
+	out := make(chan StateChange[S])
+	last := w.State()
+	w.replayer.subscribe(ctx, &replaySub{
+		deliver: func(playCtx context.Context, rec *replayRecord) bool {
+			state, ok := w.stateOf(rec)
+			if !ok {
+				return true
+			}
+			change := StateChange[S]{
+				ComponentID:   w.componentID,
+				ComponentType: w.componentType,
+				OldState:      last,
+				NewState:      state,
+				Timestamp:     rec.snapshot.Timestamp,
+			}
+			last = state
+			return send(playCtx, ctx, out, change)
+		},
+		onClose: func() { close(out) },
+	})
+	return out
+}
+
+func (w *ReplayedWatcher[S]) matches(componentType, componentID string) bool {
+	return componentType == w.componentType && componentID == w.componentID
+}
+
+// stateOf returns the state carried by rec if it is a later snapshot of the watched component.
+func (w *ReplayedWatcher[S]) stateOf(rec *replayRecord) (S, bool) {
+	s := rec.snapshot
+	if s == nil || rec.index <= w.initial || !w.matches(s.ComponentType, s.ComponentID) {
+		var zero S
+		return zero, false
+	}
+	return payloadAs[S](s.Payload)
+}
+
+// payloadAs converts a replayed payload to S.
+func payloadAs[S any](payload any) (S, bool) {
+	switch p := payload.(type) {
+	case S:
+		return p, true
+	case *S:
+		if p != nil {
+			return *p, true
+		}
+	case json.RawMessage:
+		var s S
+		if err := json.Unmarshal(p, &s); err == nil {
+			return s, true
+		}
+	}
+	var zero S
+	return zero, false
+}
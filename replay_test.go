@@ -0,0 +1,336 @@
+package introspection
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type replayState struct {
+	Status string `json:"status"`
+	Jobs   int    `json:"jobs"`
+}
+
+type replayEvent struct {
+	ID     string    `json:"id"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason"`
+}
+
+func (e replayEvent) ComponentID() string   { return e.ID }
+func (e replayEvent) ComponentType() string { return "worker" }
+func (e replayEvent) Timestamp() time.Time  { return e.At }
+func (e replayEvent) EventType() string     { return "Stopped" }
+
+func replayTypes(t *testing.T) *TypeRegistry {
+	t.Helper()
+	types := NewTypeRegistry()
+	if err := RegisterType[replayState](types, "replayState"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterType[replayEvent](types, "replayEvent"); err != nil {
+		t.Fatal(err)
+	}
+	return types
+}
+
+// recording writes a small recording of worker w1 and returns it.
+func recording(t *testing.T, types *TypeRegistry, base time.Time) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf, types)
+	steps := []error{
+		rec.RecordSnapshot(StateSnapshot{ComponentType: "worker", ComponentID: "w1", Timestamp: base, Payload: replayState{"Idle", 0}}),
+		rec.RecordSnapshot(StateSnapshot{ComponentType: "worker", ComponentID: "w2", Timestamp: base.Add(10 * time.Millisecond), Payload: replayState{"Idle", 0}}),
+		rec.RecordSnapshot(StateSnapshot{ComponentType: "worker", ComponentID: "w1", Timestamp: base.Add(20 * time.Millisecond), Payload: replayState{"Running", 2}}),
+		rec.RecordEvent(replayEvent{ID: "w1", At: base.Add(30 * time.Millisecond), Reason: "shutdown"}),
+		rec.RecordSnapshot(StateSnapshot{ComponentType: "worker", ComponentID: "w1", Timestamp: base.Add(40 * time.Millisecond), Payload: replayState{"Stopped", 0}}),
+	}
+	if err := errors.Join(steps...); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestRegisterType_Duplicate(t *testing.T) {
+	types := replayTypes(t)
+	if err := RegisterType[replayState](types, "other"); !errors.Is(err, ErrDuplicateType) {
+		t.Errorf("duplicate type: err = %v, want ErrDuplicateType", err)
+	}
+	if err := RegisterType[int](types, "replayState"); !errors.Is(err, ErrDuplicateType) {
+		t.Errorf("duplicate name: err = %v, want ErrDuplicateType", err)
+	}
+}
+
+func TestRecorder_Format(t *testing.T) {
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf := recording(t, replayTypes(t), base)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), buf)
+	}
+	var first map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["kind"] != RecordSnapshot || first["type"] != "replayState" || first["componentId"] != "w1" {
+		t.Errorf("first record = %v", first)
+	}
+	if !strings.Contains(lines[3], `"kind":"event"`) || !strings.Contains(lines[3], `"eventType":"Stopped"`) {
+		t.Errorf("event record = %s", lines[3])
+	}
+}
+
+func TestRecorder_AppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.ndjson")
+	for i := range 2 {
+		rec, err := OpenRecorder(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.RecordSnapshot(StateSnapshot{ComponentType: "c", ComponentID: "1", Payload: i}); err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := OpenReplayer(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", r.Len())
+	}
+}
+
+func TestReplayer_Streams(t *testing.T) {
+	ctx := context.Background()
+	types := replayTypes(t)
+	r, err := NewReplayer(recording(t, types, time.Now()), types, WithReplaySpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worker := ReplayWatcher[replayState](r, "worker", "w1")
+	if got := worker.State(); got.Status != "Idle" {
+		t.Errorf("initial State() = %+v, want Idle", got)
+	}
+
+	changes := worker.Watch(ctx)
+	snapshots := r.Snapshots(ctx)
+	events := r.Events(ctx)
+
+	var (
+		gotChanges   []string
+		gotSnapshots int
+		gotEvents    []ComponentEvent
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for changes != nil || snapshots != nil || events != nil {
+			select {
+			case c, ok := <-changes:
+				if !ok {
+					changes = nil
+					continue
+				}
+				gotChanges = append(gotChanges, c.OldState.Status+"->"+c.NewState.Status)
+			case _, ok := <-snapshots:
+				if !ok {
+					snapshots = nil
+					continue
+				}
+				gotSnapshots++
+			case e, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				gotEvents = append(gotEvents, e)
+			}
+		}
+	}()
+
+	if err := r.Play(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if want := "Idle->Running,Running->Stopped"; strings.Join(gotChanges, ",") != want {
+		t.Errorf("changes = %v, want %s", gotChanges, want)
+	}
+	if gotSnapshots != 4 {
+		t.Errorf("snapshots = %d, want 4", gotSnapshots)
+	}
+	if len(gotEvents) != 1 {
+		t.Fatalf("events = %v", gotEvents)
+	}
+	if e, ok := gotEvents[0].(replayEvent); !ok || e.Reason != "shutdown" {
+		t.Errorf("event = %#v, want registered replayEvent", gotEvents[0])
+	}
+	if worker.State().Status != "Stopped" || r.Position() != 5 {
+		t.Errorf("after Play: State() = %+v, Position() = %d", worker.State(), r.Position())
+	}
+	if err := r.Play(ctx); !errors.Is(err, ErrReplayStarted) {
+		t.Errorf("second Play() = %v, want ErrReplayStarted", err)
+	}
+	if _, ok := <-r.Snapshots(ctx); ok {
+		t.Error("Snapshots() after playback should be closed")
+	}
+}
+
+func TestReplayer_Unregistered(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewReplayer(recording(t, replayTypes(t), time.Now()), nil, WithReplaySpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unregistered payloads are decoded from JSON into the watched type.
+	worker := ReplayWatcher[replayState](r, "worker", "w1")
+	events := r.Events(ctx)
+	go r.Play(ctx)
+
+	var got []ComponentEvent
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != 1 {
+		t.Fatalf("events = %v", got)
+	}
+	e, ok := got[0].(RecordedEvent)
+	if !ok || e.EventType() != "Stopped" || e.ComponentID() != "w1" || !strings.Contains(string(e.Payload), "shutdown") {
+		t.Errorf("event = %#v, want RecordedEvent", got[0])
+	}
+	if worker.State().Status != "Stopped" {
+		t.Errorf("State() = %+v, want Stopped", worker.State())
+	}
+}
+
+// opaqueEvent has no exported fields, so it marshals to {}.
+type opaqueEvent struct {
+	id string
+	at time.Time
+}
+
+func (e opaqueEvent) ComponentID() string   { return e.id }
+func (e opaqueEvent) ComponentType() string { return "worker" }
+func (e opaqueEvent) Timestamp() time.Time  { return e.at }
+func (e opaqueEvent) EventType() string     { return "Opaque" }
+
+func TestReplayer_OpaqueEvent(t *testing.T) {
+	ctx := context.Background()
+	types := NewTypeRegistry()
+	if err := RegisterType[opaqueEvent](types, "opaqueEvent"); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := NewRecorder(&buf, types).RecordEvent(opaqueEvent{id: "w1", at: at}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReplayer(&buf, types, WithReplaySpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := r.Events(ctx)
+	go r.Play(ctx)
+
+	var got []ComponentEvent
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != 1 {
+		t.Fatalf("events = %v", got)
+	}
+	e := got[0]
+	if e.ComponentID() != "w1" || !e.Timestamp().Equal(at) || e.EventType() != "Opaque" || e.ComponentType() != "worker" {
+		t.Errorf("event = %#v, want the recorded identity", e)
+	}
+}
+
+func TestReplayer_Pacing(t *testing.T) {
+	ctx := context.Background()
+	types := replayTypes(t)
+
+	// The recording spans 40ms; at 2x it takes about 20ms.
+	r, err := NewReplayer(recording(t, types, time.Now()), types, WithReplaySpeed(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := r.Play(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("accelerated replay took %s, want >= 20ms", elapsed)
+	}
+
+	// Cancellation interrupts a paced replay.
+	r, _ = NewReplayer(recording(t, types, time.Now().Add(-time.Hour)), types)
+	r.records[1].Timestamp = r.records[0].Timestamp.Add(time.Hour)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := r.Play(cctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Play() = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestReplayer_Stepping(t *testing.T) {
+	ctx := context.Background()
+	types := replayTypes(t)
+	r, err := NewReplayer(recording(t, types, time.Now()), types, WithReplayStepping())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sctx, cancel := context.WithCancel(ctx)
+	snapshots := r.Snapshots(sctx)
+	played := make(chan error, 1)
+	go func() { played <- r.Play(ctx) }()
+
+	select {
+	case s := <-snapshots:
+		t.Fatalf("received %+v before Step", s)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if !r.Step() {
+		t.Fatal("Step() = false during playback")
+	}
+	if s := <-snapshots; s.ComponentID != "w1" {
+		t.Errorf("first snapshot = %+v", s)
+	}
+
+	// Once the subscriber leaves, the remaining steps are not blocked on delivery.
+	cancel()
+	for range snapshots {
+	}
+	steps := 1
+	for r.Step() {
+		steps++
+	}
+	if steps != r.Len() {
+		t.Errorf("steps = %d, want %d", steps, r.Len())
+	}
+	if err := <-played; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayer_InvalidRecord(t *testing.T) {
+	_, err := NewReplayer(strings.NewReader("{\"kind\":\"snapshot\"}\nnot json\n"), nil)
+	if !errors.Is(err, ErrInvalidRecord) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, want ErrInvalidRecord at line 2", err)
+	}
+}
@@ -0,0 +1,198 @@
+package introspection
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEventWindowSize is the number of events an EventWindow keeps when no size is given.
+const DefaultEventWindowSize = 256
+
+// SequenceConfig configures SequenceDiagram rendering.
+type SequenceConfig struct {
+	TimeFormat     string // Layout for timestamp notes (default: "15:04:05.000")
+	Relative       bool   // Show timestamps as offsets from the first event (e.g. "+120ms")
+	HideTimestamps bool   // Omit timestamp notes
+}
+
+// DefaultSequenceConfig returns the default sequence diagram configuration.
+func DefaultSequenceConfig() *SequenceConfig {
+	return &SequenceConfig{
+		TimeFormat: "15:04:05.000",
+	}
+}
+
+// SequenceDiagram renders events as a Mermaid sequence diagram, in timestamp order.
+// Events implementing InteractionEvent become messages from SourceID to TargetID;
+// other events become self-messages on their component. Participants are grouped
+// in boxes by ComponentType, which is learned from the events each component emits.
+// Component IDs, types and event types are escaped unless WithTrustedHTML is given.
+func SequenceDiagram(events []ComponentEvent, config *SequenceConfig, opts ...MermaidOption) string {
+	r := NewMermaidRenderer(opts...)
+	if config == nil {
+		config = DefaultSequenceConfig()
+	}
+	timeFormat := config.TimeFormat
+	if timeFormat == "" {
+		timeFormat = DefaultSequenceConfig().TimeFormat
+	}
+
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b ComponentEvent) int {
+		return a.Timestamp().Compare(b.Timestamp())
+	})
+
+	// Participants in order of first appearance, with their component types.
+	var participants []string
+	aliases := map[string]string{}
+	types := map[string]string{}
+	addParticipant := func(id string) {
+		if _, ok := aliases[id]; !ok {
+			aliases[id] = mermaidID(fmt.Sprintf("p%d", len(participants)))
+			participants = append(participants, id)
+		}
+	}
+	for _, e := range events {
+		if _, ok := types[e.ComponentID()]; !ok {
+			types[e.ComponentID()] = e.ComponentType()
+		}
+		if ie, ok := e.(InteractionEvent); ok {
+			addParticipant(ie.SourceID())
+			addParticipant(ie.TargetID())
+		} else {
+			addParticipant(e.ComponentID())
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("sequenceDiagram\n")
+
+	// Boxes by component type, in order of first appearance.
+	var groups []string
+	members := map[string][]string{}
+	for _, id := range participants {
+		t := types[id]
+		if _, ok := members[t]; !ok {
+			groups = append(groups, t)
+		}
+		members[t] = append(members[t], id)
+	}
+	for _, t := range groups {
+		if t == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("    box transparent %s\n", r.sequenceText(t)))
+		for _, id := range members[t] {
+			sb.WriteString(fmt.Sprintf("        participant %s as %s\n", aliases[id], r.sequenceText(id)))
+		}
+		sb.WriteString("    end\n")
+	}
+	for _, id := range members[""] {
+		sb.WriteString(fmt.Sprintf("    participant %s as %s\n", aliases[id], r.sequenceText(id)))
+	}
+
+	for _, e := range events {
+		from, to := aliases[e.ComponentID()], aliases[e.ComponentID()]
+		if ie, ok := e.(InteractionEvent); ok {
+			from, to = aliases[ie.SourceID()], aliases[ie.TargetID()]
+		}
+		sb.WriteString(fmt.Sprintf("    %s->>%s: %s\n", from, to, r.sequenceText(e.EventType())))
+
+		if config.HideTimestamps {
+			continue
+		}
+		var ts string
+		if config.Relative {
+			ts = "+" + formatDwell(e.Timestamp().Sub(events[0].Timestamp()))
+		} else {
+			ts = e.Timestamp().Format(timeFormat)
+		}
+		if from == to {
+			sb.WriteString(fmt.Sprintf("    note right of %s: %s\n", from, ts))
+		} else {
+			sb.WriteString(fmt.Sprintf("    note over %s,%s: %s\n", from, to, ts))
+		}
+	}
+
+	return sb.String()
+}
+
+// sequenceText escapes a participant name, box title or message. Sequence diagrams
+// read these to the end of the statement, so semicolons are escaped as well.
+func (r MermaidRenderer) sequenceText(s string) string {
+	parts := strings.Split(s, ";")
+	for i, part := range parts {
+		parts[i] = r.text(part)
+	}
+	return strings.Join(parts, "#59;")
+}
+
+// EventWindow keeps the most recent events of a stream, such as the output of
+// AggregateEvents, for rendering with SequenceDiagram.
+// It is safe for concurrent use.
+type EventWindow struct {
+	size   int
+	maxAge time.Duration
+
+	mu     sync.Mutex
+	events []ComponentEvent
+}
+
+// NewEventWindow creates a window holding at most size events (DefaultEventWindowSize if size <= 0).
+// If maxAge is positive, events older than maxAge relative to the newest event are dropped.
+func NewEventWindow(size int, maxAge time.Duration) *EventWindow {
+	if size <= 0 {
+		size = DefaultEventWindowSize
+	}
+	return &EventWindow{size: size, maxAge: maxAge}
+}
+
+// Add appends an event, evicting the oldest events beyond the window.
+func (w *EventWindow) Add(e ComponentEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.events = append(w.events, e)
+	if len(w.events) > w.size {
+		w.events = slices.Delete(w.events, 0, len(w.events)-w.size)
+	}
+
+	if w.maxAge > 0 {
+		newest := w.events[0].Timestamp()
+		for _, ev := range w.events {
+			if ev.Timestamp().After(newest) {
+				newest = ev.Timestamp()
+			}
+		}
+		cutoff := newest.Add(-w.maxAge)
+		w.events = slices.DeleteFunc(w.events, func(ev ComponentEvent) bool {
+			return ev.Timestamp().Before(cutoff)
+		})
+	}
+}
+
+// Consume adds every event from in until in is closed or ctx is cancelled.
+func (w *EventWindow) Consume(ctx context.Context, in <-chan ComponentEvent) {
+	for {
+		select {
+		case e, ok := <-in:
+			if !ok {
+				return
+			}
+			w.Add(e)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Events returns a copy of the events in the window, in arrival order.
+func (w *EventWindow) Events() []ComponentEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.events)
+}
@@ -0,0 +1,98 @@
+package introspection
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type seqEvent struct {
+	id, kind, typ string
+	at            time.Time
+}
+
+func (e seqEvent) ComponentID() string   { return e.id }
+func (e seqEvent) ComponentType() string { return e.typ }
+func (e seqEvent) Timestamp() time.Time  { return e.at }
+func (e seqEvent) EventType() string     { return e.kind }
+
+type seqInteraction struct {
+	seqEvent
+	source, target string
+}
+
+func (e seqInteraction) SourceID() string { return e.source }
+func (e seqInteraction) TargetID() string { return e.target }
+
+func TestSequenceDiagram(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	events := []ComponentEvent{
+		// Out of order: the diagram sorts by timestamp.
+		seqInteraction{seqEvent{"api-1", "Drain", "service", at(250)}, "api-1", "db"},
+		seqInteraction{seqEvent{"lifecycle", "SIGTERM", "controller", at(0)}, "lifecycle", "api-1"},
+		seqEvent{"api-1", "Stopped", "service", at(400)},
+		seqEvent{"api-2", "Stopped", "service", at(500)},
+	}
+
+	diagram := SequenceDiagram(events, nil)
+
+	expected := []string{
+		"sequenceDiagram\n",
+		"    box transparent controller\n        participant p0 as lifecycle\n    end\n",
+		"    box transparent service\n        participant p1 as api-1\n        participant p3 as api-2\n    end\n",
+		"    participant p2 as db\n",
+		"    p0->>p1: SIGTERM\n    note over p0,p1: 12:00:00.000\n    p1->>p2: Drain\n    note over p1,p2: 12:00:00.250\n    p1->>p1: Stopped\n    note right of p1: 12:00:00.400\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("SequenceDiagram() missing %q\n%s", want, diagram)
+		}
+	}
+
+	relative := SequenceDiagram(events, &SequenceConfig{Relative: true})
+	if !strings.Contains(relative, "note over p1,p2: +250ms") {
+		t.Errorf("relative timestamps missing\n%s", relative)
+	}
+
+	hidden := SequenceDiagram(events, &SequenceConfig{HideTimestamps: true})
+	if strings.Contains(hidden, "note") {
+		t.Errorf("HideTimestamps should omit notes\n%s", hidden)
+	}
+}
+
+func TestEventWindow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := NewEventWindow(3, 0)
+	for i := 0; i < 5; i++ {
+		w.Add(seqEvent{id: "c", kind: string(rune('a' + i)), at: base.Add(time.Duration(i) * time.Second)})
+	}
+	var kinds []string
+	for _, e := range w.Events() {
+		kinds = append(kinds, e.EventType())
+	}
+	if got := strings.Join(kinds, ""); got != "cde" {
+		t.Errorf("size-limited window = %q, want cde", got)
+	}
+
+	w = NewEventWindow(0, 2*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan ComponentEvent, 5)
+	for i := 0; i < 5; i++ {
+		in <- seqEvent{id: "c", kind: string(rune('a' + i)), at: base.Add(time.Duration(i) * time.Second)}
+	}
+	close(in)
+	w.Consume(ctx, in)
+
+	kinds = nil
+	for _, e := range w.Events() {
+		kinds = append(kinds, e.EventType())
+	}
+	if got := strings.Join(kinds, ""); got != "cde" {
+		t.Errorf("age-limited window = %q, want cde", got)
+	}
+}
@@ -0,0 +1,274 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownState is reported by StateMachine.Validate for references to undeclared states.
+	ErrUnknownState = errors.New("introspection: unknown state")
+
+	// ErrDuplicateState is reported by StateMachine.Validate when a state is declared twice.
+	ErrDuplicateState = errors.New("introspection: duplicate state")
+
+	// ErrParentCycle is reported by StateMachine.Validate when a state is its own
+	// Parent, directly or through other states.
+	ErrParentCycle = errors.New("introspection: parent cycle")
+)
+
+// DefaultActiveClass is the style class applied to the current state of a StateMachine.
+const DefaultActiveClass = "active"
+
+// StateMachine declares an arbitrary state machine for rendering.
+// Unlike StateMachineDiagram, which draws a fixed graceful-shutdown lifecycle,
+// the caller lists every state and transition:
+//
+//	m := &introspection.StateMachine{
+//		States: []introspection.State{
+//			{Name: "Pending", Initial: true},
+//			{Name: "Running"},
+//			{Name: "Failed", Final: true},
+//		},
+//		Transitions: []introspection.Transition{
+//			{From: "Pending", To: "Running", Event: "start"},
+//			{From: "Running", To: "Failed", Event: "crash", Guard: "retries > 3"},
+//		},
+//	}
+//	diagram := m.Diagram("Running")
+type StateMachine struct {
+	States      []State
+	Transitions []Transition
+
+	// ActiveClass is the style class of the current state (default: DefaultActiveClass).
+	ActiveClass string
+}
+
+// State declares a single state.
+// A state becomes composite when other states name it as their Parent.
+type State struct {
+	Name    string // Identifier, also the default label
+	Label   string // Display label (default: Name)
+	Parent  string // Enclosing composite state, empty for top-level states
+	Initial bool   // Entered from [*] when the enclosing scope is entered
+	Final   bool   // Exits to [*] of the enclosing scope
+	Note    string // Optional note attached to the state
+	Class   string // Optional style class applied regardless of the current state
+}
+
+// Transition declares an edge between two states.
+type Transition struct {
+	From  string
+	To    string
+	Event string // Transition label
+	Guard string // Optional guard condition, rendered as "Event [Guard]"
+}
+
+// Validate reports undeclared states referenced by transitions or parents, duplicate
+// states, and states that are their own parent, directly or through a Parent cycle.
+// Build and Diagram render invalid machines as best they can; Validate is for catching mistakes early.
+func (m *StateMachine) Validate() error {
+	var errs []error
+
+	declared := make(map[string]bool, len(m.States))
+	for _, s := range m.States {
+		if declared[s.Name] {
+			errs = append(errs, fmt.Errorf("state %q: %w", s.Name, ErrDuplicateState))
+		}
+		declared[s.Name] = true
+	}
+
+	parents := make(map[string][]string)
+	for _, s := range m.States {
+		if s.Parent != "" && !declared[s.Parent] {
+			errs = append(errs, fmt.Errorf("state %q: parent %q: %w", s.Name, s.Parent, ErrUnknownState))
+		}
+		if s.Parent != "" {
+			parents[s.Name] = append(parents[s.Name], s.Parent)
+		}
+	}
+
+	reported := make(map[string]bool)
+	for _, s := range m.States {
+		if s.Parent != "" && !reported[s.Name] && isOwnAncestor(s.Name, parents) {
+			reported[s.Name] = true
+			errs = append(errs, fmt.Errorf("state %q: parent %q: %w", s.Name, s.Parent, ErrParentCycle))
+		}
+	}
+
+	for _, t := range m.Transitions {
+		for _, name := range []string{t.From, t.To} {
+			if !declared[name] {
+				errs = append(errs, fmt.Errorf("transition %s -> %s: %q: %w", t.From, t.To, name, ErrUnknownState))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// isOwnAncestor reports whether name can be reached by following parents from name.
+func isOwnAncestor(name string, parents map[string][]string) bool {
+	seen := map[string]bool{}
+	queue := append([]string(nil), parents[name]...)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == name {
+			return true
+		}
+		if !seen[p] {
+			seen[p] = true
+			queue = append(queue, parents[p]...)
+		}
+	}
+	return false
+}
+
+// Build builds the state graph of m, highlighting the current state.
+// Composite states become subgraphs, each with its own initial and final pseudo-states;
+// only leaf states can be highlighted.
+func (m *StateMachine) Build(current string, opts ...MermaidOption) *Graph {
+	options := newMermaidOptions(opts)
+
+	activeClass := m.ActiveClass
+	if activeClass == "" {
+		activeClass = DefaultActiveClass
+	}
+
+	ids := newStateIDs()
+	children := map[string][]State{}
+	for _, s := range m.States {
+		children[s.Parent] = append(children[s.Parent], s)
+	}
+
+	// scope builds the states declared directly inside parent. A state that is
+	// already being expanded, because of a Parent cycle, is skipped.
+	expanding := map[string]bool{}
+	var scope func(parent string) ([]*Node, []*Edge, []*Subgraph)
+	scope = func(parent string) ([]*Node, []*Edge, []*Subgraph) {
+		startID, endID := StartStateID, EndStateID
+		if parent != "" {
+			startID, endID = StartStateID+"_"+ids.state(parent), EndStateID+"_"+ids.state(parent)
+		}
+
+		var nodes []*Node
+		var edges []*Edge
+		var subgraphs []*Subgraph
+		var hasStart, hasEnd bool
+
+		for _, s := range children[parent] {
+			if expanding[s.Name] {
+				continue
+			}
+			label := s.Label
+			if label == "" {
+				label = s.Name
+			}
+
+			if _, composite := children[s.Name]; composite && s.Name != "" {
+				sg := &Subgraph{ID: ids.state(s.Name), Label: label}
+				expanding[s.Name] = true
+				sg.Nodes, sg.Edges, sg.Subgraphs = scope(s.Name)
+				delete(expanding, s.Name)
+				subgraphs = append(subgraphs, sg)
+			} else {
+				n := &Node{ID: ids.state(s.Name), Label: label, Name: s.Name, Data: s}
+				if s.Class != "" {
+					n.Classes = append(n.Classes, s.Class)
+				}
+				if s.Name == current {
+					n.Classes = append(n.Classes, activeClass)
+				}
+				nodes = append(nodes, n)
+			}
+
+			if s.Initial {
+				hasStart = true
+				edges = append(edges, &Edge{From: startID, To: ids.state(s.Name)})
+			}
+			if s.Final {
+				hasEnd = true
+				edges = append(edges, &Edge{From: ids.state(s.Name), To: endID})
+			}
+		}
+
+		if hasStart {
+			nodes = append([]*Node{{ID: startID}}, nodes...)
+		}
+		if hasEnd {
+			nodes = append(nodes, &Node{ID: endID})
+		}
+		return nodes, edges, subgraphs
+	}
+
+	g := &Graph{Kind: StateGraph, Styles: options.Styles}
+	g.Nodes, g.Edges, g.Subgraphs = scope("")
+
+	for _, t := range m.Transitions {
+		label := t.Event
+		if t.Guard != "" {
+			if label != "" {
+				label += " "
+			}
+			label += "[" + t.Guard + "]"
+		}
+		g.Edges = append(g.Edges, &Edge{From: ids.state(t.From), To: ids.state(t.To), Label: label})
+	}
+
+	for _, s := range m.States {
+		if s.Note != "" {
+			g.Notes = append(g.Notes, &Note{Target: ids.state(s.Name), Position: "right of", Text: s.Note})
+		}
+	}
+
+	return g
+}
+
+// Diagram renders m as a Mermaid state diagram, highlighting the current state.
+func (m *StateMachine) Diagram(current string, opts ...MermaidOption) string {
+	return NewMermaidRenderer(opts...).Render(m.Build(current, opts...))
+}
+
+// WatchStateMachine renders m each time the state of w enters a different state,
+// as reported by stateOf. The first diagram reflects w.State().
+// The channel is closed when ctx is cancelled or the watch channel closes.
+func WatchStateMachine[S any](ctx context.Context, m *StateMachine, w TypedWatcher[S], stateOf func(S) string, opts ...MermaidOption) <-chan string {
+	out := make(chan string, 1)
+	changes := w.Watch(ctx)
+
+	go func() {
+		defer close(out)
+
+		current := stateOf(w.State())
+		select {
+		case out <- m.Diagram(current, opts...):
+		case <-ctx.Done():
+			return
+		}
+
+		for {
+			select {
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				next := stateOf(change.NewState)
+				if next == current {
+					continue
+				}
+				current = next
+				select {
+				case out <- m.Diagram(current, opts...):
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
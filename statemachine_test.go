@@ -0,0 +1,192 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lifecycleMachine() *StateMachine {
+	return &StateMachine{
+		States: []State{
+			{Name: "Pending", Initial: true},
+			{Name: "Starting"},
+			{Name: "Running", Label: "Up and running"},
+			{Name: "Suspended", Note: "        Paused by operator\n"},
+			{Name: "Failed", Class: "failed"},
+			{Name: "Restarting"},
+			{Name: "Backoff", Parent: "Restarting", Initial: true},
+			{Name: "Relaunch", Parent: "Restarting", Final: true},
+			{Name: "Stopped", Final: true},
+		},
+		Transitions: []Transition{
+			{From: "Pending", To: "Starting", Event: "schedule"},
+			{From: "Starting", To: "Running", Event: "ready"},
+			{From: "Running", To: "Suspended", Event: "pause"},
+			{From: "Suspended", To: "Running", Event: "resume"},
+			{From: "Running", To: "Failed", Event: "crash"},
+			{From: "Failed", To: "Restarting", Guard: "restarts < 3"},
+			{From: "Backoff", To: "Relaunch", Event: "timer"},
+			{From: "Restarting", To: "Starting"},
+			{From: "Running", To: "Stopped", Event: "stop", Guard: "drained"},
+		},
+	}
+}
+
+func TestStateMachine_Diagram(t *testing.T) {
+	diagram := lifecycleMachine().Diagram("Running")
+
+	expected := []string{
+		"stateDiagram-v2\n",
+		"    state \"Up and running\" as Running\n",
+		"    [*] --> Pending\n",
+		"    Stopped --> [*]\n",
+		"    state Restarting {\n        [*] --> Backoff\n        Relaunch --> [*]\n    }\n",
+		"    Failed --> Restarting: [restarts < 3]\n",
+		"    Running --> Stopped: stop [drained]\n",
+		"    Backoff --> Relaunch: timer\n",
+		"    note right of Suspended\n        Paused by operator\n    end note\n",
+		"    class Running active\n",
+		"    class Failed failed\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("Diagram() missing %q\n%s", want, diagram)
+		}
+	}
+	if strings.Contains(diagram, "class Pending") {
+		t.Errorf("Diagram() should only highlight the current state\n%s", diagram)
+	}
+}
+
+func TestStateMachine_Build(t *testing.T) {
+	g := lifecycleMachine().Build("Backoff")
+
+	if len(g.Subgraphs) != 1 || g.Subgraphs[0].ID != "Restarting" {
+		t.Fatalf("Subgraphs = %v, want composite Restarting", g.Subgraphs)
+	}
+	sg := g.Subgraphs[0]
+	if got := sg.Nodes[0].ID; got != StartStateID+"_Restarting" {
+		t.Errorf("composite start = %q", got)
+	}
+	if got := sg.Nodes[len(sg.Nodes)-1].ID; got != EndStateID+"_Restarting" {
+		t.Errorf("composite end = %q", got)
+	}
+	if n := g.Node("Backoff"); n == nil || len(n.Classes) != 1 || n.Classes[0] != DefaultActiveClass {
+		t.Errorf("Backoff = %+v, want active", n)
+	}
+	if g.Node("Restarting") != nil {
+		t.Error("composite state should be a subgraph, not a node")
+	}
+
+	// Every renderer handles composite states.
+	dot := DOTRenderer{}.Render(g)
+	if !strings.Contains(dot, "subgraph cluster_Restarting {") {
+		t.Errorf("DOT missing composite cluster\n%s", dot)
+	}
+	uml := PlantUMLRenderer{}.Render(g)
+	if !strings.Contains(uml, "state \"Restarting\" as Restarting {\n    state Backoff <<active>>\n") {
+		t.Errorf("PlantUML missing composite state\n%s", uml)
+	}
+}
+
+func TestStateMachine_Validate(t *testing.T) {
+	if err := lifecycleMachine().Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	m := &StateMachine{
+		States: []State{
+			{Name: "A"},
+			{Name: "A"},
+			{Name: "B", Parent: "Missing"},
+		},
+		Transitions: []Transition{{From: "A", To: "C"}},
+	}
+	err := m.Validate()
+	if !errors.Is(err, ErrDuplicateState) {
+		t.Errorf("Validate() = %v, want ErrDuplicateState", err)
+	}
+	if !errors.Is(err, ErrUnknownState) {
+		t.Errorf("Validate() = %v, want ErrUnknownState", err)
+	}
+	for _, want := range []string{`"Missing"`, `"C"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %s", err, want)
+		}
+	}
+}
+
+func TestWatchStateMachine(t *testing.T) {
+	type phase struct {
+		Name    string
+		Attempt int
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBroadcaster("task", "t1", phase{Name: "Pending"})
+	defer b.Close()
+
+	diagrams := WatchStateMachine(ctx, lifecycleMachine(), TypedWatcher[phase](b), func(p phase) string { return p.Name })
+
+	next := func() string {
+		t.Helper()
+		select {
+		case d := <-diagrams:
+			return d
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for diagram")
+			return ""
+		}
+	}
+
+	if d := next(); !strings.Contains(d, "class Pending active") {
+		t.Errorf("initial diagram should highlight Pending\n%s", d)
+	}
+
+	b.Publish(phase{Name: "Pending"}, phase{Name: "Pending", Attempt: 1}) // same state, no diagram
+	b.Publish(phase{Name: "Pending", Attempt: 1}, phase{Name: "Starting"})
+
+	if d := next(); !strings.Contains(d, "class Starting active") {
+		t.Errorf("diagram should highlight Starting\n%s", d)
+	}
+
+	cancel()
+	for range diagrams {
+	}
+}
+
+func TestStateMachine_ParentCycles(t *testing.T) {
+	m := &StateMachine{
+		States: []State{
+			{Name: "A", Initial: true},
+			{Name: "A", Parent: "A"},
+			{Name: "B", Parent: "C"},
+			{Name: "C", Parent: "B"},
+			{Name: "D", Parent: "A"},
+		},
+	}
+
+	err := m.Validate()
+	if !errors.Is(err, ErrParentCycle) {
+		t.Fatalf("Validate() = %v, want ErrParentCycle", err)
+	}
+	for _, want := range []string{`state "A": parent "A"`, `state "B"`, `state "C"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), `state "D"`) {
+		t.Errorf("Validate() = %v, D is inside a cycle but not on it", err)
+	}
+
+	// Build renders the machine without recursing forever.
+	diagram := m.Diagram("D")
+	if !strings.Contains(diagram, "state A {") || !strings.Contains(diagram, "class D active") {
+		t.Errorf("Diagram() =\n%s", diagram)
+	}
+}
@@ -0,0 +1,145 @@
+package introspection
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// StateStore keeps the latest StateSnapshot per component.
+// It is safe for concurrent use and is typically fed from an aggregated snapshot stream:
+//
+//	store := NewStateStore()
+//	go store.Consume(ctx, agg.Snapshots(ctx))
+//
+// Every applied snapshot increments a version counter; WaitForChange blocks until the
+// version moves past a given value, which makes HTTP long-polling straightforward.
+type StateStore struct {
+	mu      sync.RWMutex
+	states  map[ComponentKey]StateSnapshot
+	version uint64
+	changed chan struct{} // closed and replaced whenever version increments
+}
+
+// NewStateStore creates an empty StateStore.
+func NewStateStore() *StateStore {
+	return &StateStore{
+		states:  make(map[ComponentKey]StateSnapshot),
+		changed: make(chan struct{}),
+	}
+}
+
+// Update records snapshot as the latest state of its component.
+func (s *StateStore) Update(snapshot StateSnapshot) {
+	key := ComponentKey{ComponentType: snapshot.ComponentType, ComponentID: snapshot.ComponentID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key] = snapshot
+	s.bump()
+}
+
+// Delete forgets a component. It reports whether the component was present.
+func (s *StateStore) Delete(componentType, componentID string) bool {
+	key := ComponentKey{ComponentType: componentType, ComponentID: componentID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.states[key]; !ok {
+		return false
+	}
+	delete(s.states, key)
+	s.bump()
+	return true
+}
+
+// bump increments the version and wakes every waiter. The caller must hold s.mu.
+func (s *StateStore) bump() {
+	s.version++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Consume applies every snapshot received from in until it is closed or ctx is cancelled.
+func (s *StateStore) Consume(ctx context.Context, in <-chan StateSnapshot) {
+	for {
+		select {
+		case snapshot, ok := <-in:
+			if !ok {
+				return
+			}
+			s.Update(snapshot)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Get returns the latest snapshot of a single component.
+func (s *StateStore) Get(componentType, componentID string) (StateSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, ok := s.states[ComponentKey{ComponentType: componentType, ComponentID: componentID}]
+	return snapshot, ok
+}
+
+// Snapshot returns a point-in-time copy of the latest state of every component.
+func (s *StateStore) Snapshot() map[ComponentKey]StateSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[ComponentKey]StateSnapshot, len(s.states))
+	for key, snapshot := range s.states {
+		result[key] = snapshot
+	}
+	return result
+}
+
+// List returns the latest snapshot of every component, sorted by type and ID.
+func (s *StateStore) List() []StateSnapshot {
+	s.mu.RLock()
+	result := make([]StateSnapshot, 0, len(s.states))
+	for _, snapshot := range s.states {
+		result = append(result, snapshot)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ComponentType != result[j].ComponentType {
+			return result[i].ComponentType < result[j].ComponentType
+		}
+		return result[i].ComponentID < result[j].ComponentID
+	})
+	return result
+}
+
+// Version returns the number of changes applied so far.
+func (s *StateStore) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// WaitForChange blocks until the store version is greater than version and returns the new version.
+// It returns immediately if the store has already moved past version.
+// If ctx is cancelled first, it returns the current version and ctx.Err().
+func (s *StateStore) WaitForChange(ctx context.Context, version uint64) (uint64, error) {
+	for {
+		s.mu.RLock()
+		current, changed := s.version, s.changed
+		s.mu.RUnlock()
+
+		if current > version {
+			return current, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return current, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStateStore_KeepsLatestPerComponent(t *testing.T) {
+	store := NewStateStore()
+
+	store.Update(StateSnapshot{ComponentType: "worker", ComponentID: "a", Payload: 1})
+	store.Update(StateSnapshot{ComponentType: "worker", ComponentID: "a", Payload: 2})
+	store.Update(StateSnapshot{ComponentType: "supervisor", ComponentID: "a", Payload: 3})
+
+	if got := store.Version(); got != 3 {
+		t.Errorf("Version() = %d, want 3", got)
+	}
+
+	snap, ok := store.Get("worker", "a")
+	if !ok || snap.Payload != 2 {
+		t.Errorf("Get(worker, a) = %v, %v; want payload 2", snap, ok)
+	}
+
+	list := store.List()
+	if len(list) != 2 || list[0].ComponentType != "supervisor" || list[1].ComponentType != "worker" {
+		t.Errorf("List() = %v, want supervisor then worker", list)
+	}
+
+	// Snapshot returns a copy that is not affected by later updates.
+	copied := store.Snapshot()
+	store.Update(StateSnapshot{ComponentType: "worker", ComponentID: "a", Payload: 4})
+	if copied[ComponentKey{ComponentType: "worker", ComponentID: "a"}].Payload != 2 {
+		t.Error("Snapshot() copy was modified by a later Update")
+	}
+
+	if !store.Delete("worker", "a") || store.Delete("worker", "a") {
+		t.Error("Delete() should report presence exactly once")
+	}
+	if _, ok := store.Get("worker", "a"); ok {
+		t.Error("Get() after Delete should report missing")
+	}
+}
+
+func TestStateStore_WaitForChange(t *testing.T) {
+	store := NewStateStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := make(chan uint64, 1)
+	go func() {
+		v, err := store.WaitForChange(ctx, 0)
+		if err != nil {
+			t.Errorf("WaitForChange() error = %v", err)
+		}
+		result <- v
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	store.Update(StateSnapshot{ComponentType: "worker", ComponentID: "a"})
+
+	select {
+	case v := <-result:
+		if v != 1 {
+			t.Errorf("WaitForChange() = %d, want 1", v)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("WaitForChange() did not return after Update")
+	}
+
+	// Already past the requested version: returns immediately.
+	if v, err := store.WaitForChange(ctx, 0); v != 1 || err != nil {
+		t.Errorf("WaitForChange(0) = %d, %v; want 1, nil", v, err)
+	}
+}
+
+func TestStateStore_WaitForChange_Cancelled(t *testing.T) {
+	store := NewStateStore()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	v, err := store.WaitForChange(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForChange() error = %v, want DeadlineExceeded", err)
+	}
+	if v != 0 {
+		t.Errorf("WaitForChange() = %d, want 0", v)
+	}
+}
+
+func TestStateStore_Consume(t *testing.T) {
+	store := NewStateStore()
+	in := make(chan StateSnapshot, 3)
+	in <- StateSnapshot{ComponentType: "worker", ComponentID: "a", Payload: 1}
+	in <- StateSnapshot{ComponentType: "worker", ComponentID: "b", Payload: 1}
+	in <- StateSnapshot{ComponentType: "worker", ComponentID: "a", Payload: 2}
+	close(in)
+
+	store.Consume(context.Background(), in)
+
+	if len(store.Snapshot()) != 2 {
+		t.Errorf("Snapshot() has %d components, want 2", len(store.Snapshot()))
+	}
+	if snap, _ := store.Get("worker", "a"); snap.Payload != 2 {
+		t.Errorf("Get(worker, a).Payload = %v, want 2", snap.Payload)
+	}
+}
@@ -0,0 +1,226 @@
+package introspection
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// BackpressurePolicy decides what a stream does when its consumer falls behind.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock waits for the consumer once the buffer is full.
+	// Producers are slowed down to the consumer's pace. This is the default.
+	BackpressureBlock BackpressurePolicy = iota
+
+	// BackpressureDropOldest discards the oldest buffered message to make room for a new one.
+	BackpressureDropOldest
+
+	// BackpressureDropNewest discards incoming messages while the buffer is full.
+	BackpressureDropNewest
+
+	// BackpressureCoalesce keeps only the latest buffered message per component.
+	// A new message replaces a pending one from the same component; if the buffer
+	// is full of distinct components, the oldest is discarded.
+	BackpressureCoalesce
+)
+
+// String returns the policy name.
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureBlock:
+		return "block"
+	case BackpressureDropOldest:
+		return "drop-oldest"
+	case BackpressureDropNewest:
+		return "drop-newest"
+	case BackpressureCoalesce:
+		return "coalesce"
+	default:
+		return "unknown"
+	}
+}
+
+// StreamOption configures buffering of a snapshot, change or event stream.
+type StreamOption func(*StreamOptions)
+
+// StreamOptions holds stream buffering options.
+type StreamOptions struct {
+	BufferSize int                // Number of messages buffered for the consumer
+	Policy     BackpressurePolicy // What to do when the buffer is full
+	Stats      *StreamStats       // Optional counters, shared by every stream built with these options
+}
+
+// WithBufferSize sets the number of messages buffered for the consumer.
+// Values below 1 are treated as 1.
+func WithBufferSize(n int) StreamOption {
+	return func(o *StreamOptions) {
+		if n < 1 {
+			n = 1
+		}
+		o.BufferSize = n
+	}
+}
+
+// WithBackpressure sets the policy applied when the consumer falls behind.
+func WithBackpressure(p BackpressurePolicy) StreamOption {
+	return func(o *StreamOptions) {
+		o.Policy = p
+	}
+}
+
+// WithStreamStats attaches counters that record delivered and dropped messages.
+func WithStreamStats(stats *StreamStats) StreamOption {
+	return func(o *StreamOptions) {
+		o.Stats = stats
+	}
+}
+
+// StreamStats counts messages delivered to and dropped from a stream.
+// It is safe for concurrent use; the zero value is ready to use.
+type StreamStats struct {
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Delivered returns the number of messages handed to the consumer.
+func (s *StreamStats) Delivered() uint64 {
+	return s.delivered.Load()
+}
+
+// Dropped returns the number of messages discarded or replaced by the backpressure policy.
+func (s *StreamStats) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *StreamStats) addDelivered() {
+	if s != nil {
+		s.delivered.Add(1)
+	}
+}
+
+func (s *StreamStats) addDropped() {
+	if s != nil {
+		s.dropped.Add(1)
+	}
+}
+
+// newStreamOptions applies opts on top of a default buffer size with the blocking policy.
+func newStreamOptions(defaultBuffer int, opts []StreamOption) *StreamOptions {
+	options := &StreamOptions{BufferSize: defaultBuffer, Policy: BackpressureBlock}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// BufferSnapshots decouples a snapshot stream from its consumer according to opts.
+// With a non-blocking policy the input is always drained promptly, so producers
+// upstream are never stalled by a slow consumer. Coalescing is keyed by
+// ComponentType and ComponentID.
+func BufferSnapshots(ctx context.Context, in <-chan StateSnapshot, opts ...StreamOption) <-chan StateSnapshot {
+	return bufferStream(ctx, in, newStreamOptions(64, opts), snapshotKey)
+}
+
+// BufferEvents decouples an event stream from its consumer according to opts.
+// Coalescing is keyed by the event's ComponentType and ComponentID.
+func BufferEvents(ctx context.Context, in <-chan ComponentEvent, opts ...StreamOption) <-chan ComponentEvent {
+	return bufferStream(ctx, in, newStreamOptions(64, opts), eventKey)
+}
+
+func snapshotKey(s StateSnapshot) string {
+	return s.ComponentType + "/" + s.ComponentID
+}
+
+func eventKey(e ComponentEvent) string {
+	return e.ComponentType() + "/" + e.ComponentID()
+}
+
+func changeKey[S any](c StateChange[S]) string {
+	return c.ComponentType + "/" + c.ComponentID
+}
+
+// bufferStream forwards in to the returned channel, applying the backpressure policy.
+// The output is closed once in is closed and drained, or when ctx is cancelled.
+func bufferStream[T any](ctx context.Context, in <-chan T, o *StreamOptions, key func(T) string) <-chan T {
+	size := o.BufferSize
+	if size < 1 {
+		size = 1
+	}
+
+	if o.Policy == BackpressureBlock {
+		out := make(chan T, size)
+		go func() {
+			defer close(out)
+			for v := range in {
+				select {
+				case out <- v:
+					o.Stats.addDelivered()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return out
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		queue := make([]T, 0, size)
+		for in != nil || len(queue) > 0 {
+			var send chan T
+			var next T
+			if len(queue) > 0 {
+				send = out
+				next = queue[0]
+			}
+
+			select {
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				queue = enqueue(queue, v, size, o, key)
+			case send <- next:
+				var zero T
+				queue[0] = zero
+				queue = queue[1:]
+				o.Stats.addDelivered()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// enqueue appends v to queue according to the non-blocking policy in o.
+func enqueue[T any](queue []T, v T, size int, o *StreamOptions, key func(T) string) []T {
+	if o.Policy == BackpressureCoalesce && key != nil {
+		k := key(v)
+		for i := range queue {
+			if key(queue[i]) == k {
+				queue[i] = v
+				o.Stats.addDropped()
+				return queue
+			}
+		}
+	}
+
+	if len(queue) < size {
+		return append(queue, v)
+	}
+
+	o.Stats.addDropped()
+	if o.Policy == BackpressureDropNewest {
+		return queue
+	}
+
+	// Drop oldest (also the fallback for coalesce with a full buffer of distinct keys).
+	copy(queue, queue[1:])
+	queue[len(queue)-1] = v
+	return queue
+}
@@ -0,0 +1,218 @@
+package introspection
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// feedSnapshots returns a closed channel pre-filled with one snapshot per ID.
+func feedSnapshots(ids ...string) <-chan StateSnapshot {
+	ch := make(chan StateSnapshot, len(ids))
+	for i, id := range ids {
+		ch <- StateSnapshot{ComponentType: "worker", ComponentID: id, Payload: i}
+	}
+	close(ch)
+	return ch
+}
+
+// drainAfterSettle waits for the buffer stage to consume its input, then collects its output.
+func drainAfterSettle(t *testing.T, in <-chan StateSnapshot, out <-chan StateSnapshot) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(1 * time.Second)
+	for len(in) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var got []string
+	for snap := range out {
+		got = append(got, fmt.Sprintf("%s:%v", snap.ComponentID, snap.Payload))
+	}
+	return got
+}
+
+func TestBufferSnapshots_Policies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  BackpressurePolicy
+		ids     []string
+		want    []string
+		dropped uint64
+	}{
+		{
+			name:    "drop newest",
+			policy:  BackpressureDropNewest,
+			ids:     []string{"a", "b", "c", "d", "e"},
+			want:    []string{"a:0", "b:1"},
+			dropped: 3,
+		},
+		{
+			name:    "drop oldest",
+			policy:  BackpressureDropOldest,
+			ids:     []string{"a", "b", "c", "d", "e"},
+			want:    []string{"d:3", "e:4"},
+			dropped: 3,
+		},
+		{
+			name:    "coalesce",
+			policy:  BackpressureCoalesce,
+			ids:     []string{"a", "b", "a", "a", "b"},
+			want:    []string{"a:3", "b:4"},
+			dropped: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			stats := &StreamStats{}
+			in := feedSnapshots(tt.ids...)
+			out := BufferSnapshots(ctx, in,
+				WithBufferSize(2),
+				WithBackpressure(tt.policy),
+				WithStreamStats(stats),
+			)
+
+			got := drainAfterSettle(t, in, out)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+			if stats.Dropped() != tt.dropped {
+				t.Errorf("Dropped() = %d, want %d", stats.Dropped(), tt.dropped)
+			}
+			if stats.Delivered() != uint64(len(tt.want)) {
+				t.Errorf("Delivered() = %d, want %d", stats.Delivered(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestBufferSnapshots_BlockDeliversEverything(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stats := &StreamStats{}
+	out := BufferSnapshots(ctx, feedSnapshots("a", "b", "c", "d"), WithBufferSize(1), WithStreamStats(stats))
+
+	count := 0
+	for range out {
+		count++
+	}
+
+	if count != 4 || stats.Delivered() != 4 || stats.Dropped() != 0 {
+		t.Errorf("count=%d delivered=%d dropped=%d, want 4/4/0", count, stats.Delivered(), stats.Dropped())
+	}
+}
+
+func TestBufferEvents_ClosesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan ComponentEvent)
+
+	out := BufferEvents(ctx, in, WithBackpressure(BackpressureDropOldest))
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("Channel should be closed after context cancellation")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for channel close")
+	}
+}
+
+func TestAggregateWith_Options(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// waitDropped waits until the buffer stage has dropped n values.
+	waitDropped := func(stats *StreamStats, n uint64) {
+		t.Helper()
+		deadline := time.Now().Add(1 * time.Second)
+		for stats.Dropped() < n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if stats.Dropped() != n {
+			t.Fatalf("Dropped() = %d, want %d", stats.Dropped(), n)
+		}
+	}
+
+	source := NewMockEventSource()
+	eventStats := &StreamStats{}
+	events := AggregateEventsWith(ctx, []EventSource{source},
+		WithBufferSize(2), WithBackpressure(BackpressureDropOldest), WithStreamStats(eventStats))
+	for i := 0; i < 5; i++ {
+		source.SendEvent(&MockComponent{id: fmt.Sprintf("e%d", i), compType: "worker"})
+	}
+	waitDropped(eventStats, 3)
+	if a, b := <-events, <-events; a.ComponentID() != "e3" || b.ComponentID() != "e4" {
+		t.Errorf("events = %s, %s; want e3, e4", a.ComponentID(), b.ComponentID())
+	}
+
+	watcher := NewMockTypedWatcher(MockState{})
+	snapshotStats := &StreamStats{}
+	snapshots := AggregateWatchersWith(ctx, []interface{}{watcher},
+		WithBufferSize(1), WithBackpressure(BackpressureCoalesce), WithStreamStats(snapshotStats))
+	for _, v := range []string{"a", "b", "c"} {
+		watcher.SendChange(StateChange[MockState]{NewState: MockState{Value: v}})
+	}
+	waitDropped(snapshotStats, 2)
+	if s := <-snapshots; s.Payload.(MockState).Value != "c" {
+		t.Errorf("snapshot = %+v, want the latest state", s)
+	}
+}
+
+func TestBroadcaster_SlowSubscriberDoesNotStallPublish(t *testing.T) {
+	stats := &StreamStats{}
+	b := NewBroadcaster("processor", "proc-1", MockState{},
+		WithBufferSize(1),
+		WithBackpressure(BackpressureCoalesce),
+		WithStreamStats(stats),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := b.Watch(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			b.Publish(MockState{}, MockState{Value: fmt.Sprint(i)})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Publish blocked on a subscriber that never reads")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case change := <-sub:
+		if change.NewState.Value != "99" {
+			t.Errorf("NewState = %q, want latest %q", change.NewState.Value, "99")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for coalesced change")
+	}
+
+	if stats.Dropped() != 99 {
+		t.Errorf("Dropped() = %d, want 99", stats.Dropped())
+	}
+}
+
+func TestBackpressurePolicy_String(t *testing.T) {
+	if BackpressureCoalesce.String() != "coalesce" {
+		t.Errorf("String() = %q, want %q", BackpressureCoalesce.String(), "coalesce")
+	}
+	if BackpressurePolicy(42).String() != "unknown" {
+		t.Errorf("String() for invalid policy = %q, want %q", BackpressurePolicy(42).String(), "unknown")
+	}
+}
@@ -0,0 +1,224 @@
+package introspection
+
+import (
+	"cmp"
+	"strings"
+)
+
+// Mermaid classes added to the nodes of a tree diff.
+const (
+	DiffAddedClass   = "added"
+	DiffRemovedClass = "removed"
+	DiffChangedClass = "changed"
+)
+
+// DiffStyles returns the Mermaid class definitions for tree diff nodes.
+// They are appended after the diagram styles, so they take precedence over status classes.
+func DiffStyles() string {
+	return `    classDef added fill:#d4edda,stroke:#28a745,stroke-width:3px;
+    classDef removed fill:#f8f9fa,stroke:#dc3545,stroke-width:3px,stroke-dasharray: 5 5,color:#6c757d;
+    classDef changed stroke:#fd7e14,stroke-width:3px;
+`
+}
+
+// TreeDiffDiagram renders the union of two snapshots of a tree as a Mermaid flowchart.
+// Nodes only present in after are marked "added", nodes only present in before are
+// marked "removed", and nodes whose Status, PID or Metadata changed are marked "changed";
+// each label is annotated with the difference, e.g. "Status: Running → Failed".
+func TreeDiffDiagram(before, after any, config *DiagramConfig, opts ...MermaidOption) string {
+	return NewMermaidRenderer(opts...).Render(BuildTreeDiffGraph(before, after, config, opts...))
+}
+
+// BuildTreeDiffGraph builds the graph rendered by TreeDiffDiagram.
+// Children are matched by Name when every child on both sides has a unique, non-empty
+// name, and by position otherwise. Removed children keep their position among their siblings.
+func BuildTreeDiffGraph(before, after any, config *DiagramConfig, opts ...MermaidOption) *Graph {
+	if config == nil {
+		config = DefaultDiagramConfig()
+	}
+	options := newMermaidOptions(opts)
+
+	g := &Graph{Kind: FlowchartGraph, Styles: options.Styles + DiffStyles()}
+	ids := newNodeIDs(config)
+	visits := newTreeVisits(true)
+
+	var visit func(pair childPair, id string)
+	visit = func(pair childPair, id string) {
+		self, kids := pair.visitKeys()
+		visits.enter(self, kids, id)
+		defer visits.leave(self, kids)
+
+		var node *Node
+		var pairs []childPair
+
+		switch {
+		case pair.before == nil:
+			node = newTreeNode(id, pair.a, pair.after, config)
+			annotateDiffNode(node, DiffAddedClass, "added")
+			pairs = pairChildren(treeFields{}, pair.a, config.Fields)
+		case pair.after == nil:
+			node = newTreeNode(id, pair.b, pair.before, config)
+			annotateDiffNode(node, DiffRemovedClass, "removed")
+			pairs = pairChildren(pair.b, treeFields{}, config.Fields)
+		default:
+			node = newTreeNode(id, pair.a, pair.after, config)
+			if changes := diffTreeFields(pair.b, pair.a); len(changes) > 0 {
+				annotateDiffNode(node, DiffChangedClass, changes...)
+			}
+			pairs = pairChildren(pair.b, pair.a, config.Fields)
+		}
+		g.Nodes = append(g.Nodes, node)
+
+		for i, child := range pairs {
+			if existing, seen := visits.seen(child.visitKeys()); seen {
+				g.Edges = append(g.Edges, &Edge{From: id, To: existing, Back: true})
+				continue
+			}
+			childID := ids.child(id, i, child.name(), child.node())
+			visit(child, childID)
+			g.Edges = append(g.Edges, &Edge{From: id, To: childID})
+		}
+	}
+
+	root := childPair{before: before, after: after}
+	if before != nil {
+		root.b = readTreeNode(before, config.Fields)
+	}
+	if after != nil {
+		root.a = readTreeNode(after, config.Fields)
+	}
+	visit(root, ids.unique(config.SecondaryID))
+	return g
+}
+
+// childPair holds the before and after versions of a child, each with its fields
+// read once; either is nil for an added or removed child.
+type childPair struct {
+	before, after any
+	b, a          treeFields
+}
+
+// node returns the after version of the child, or the before version if it was removed.
+func (p childPair) node() any {
+	if p.after != nil {
+		return p.after
+	}
+	return p.before
+}
+
+// name returns the name of the after version of the child, or the before version if it was removed.
+func (p childPair) name() string {
+	if p.after != nil {
+		return p.a.name
+	}
+	return p.b.name
+}
+
+// visitKeys returns the keys identifying the pair in treeVisits. Each key pairs the
+// references of both versions, and is only set if every version present has one.
+func (p childPair) visitKeys() (self, kids treeKey) {
+	pairKey := func(before, after treeRef) treeKey {
+		if (p.before != nil && !before.valid()) || (p.after != nil && !after.valid()) {
+			return treeKey{}
+		}
+		return treeKey{before, after}
+	}
+	return pairKey(p.b.self, p.a.self), pairKey(p.b.kids, p.a.kids)
+}
+
+// diffTreeFields returns the formatted differences in Status, PID and Metadata.
+func diffTreeFields(before, after treeFields) []string {
+	type fields struct {
+		Status   string
+		PID      int
+		Metadata map[string]string
+	}
+	changes := DiffStates(
+		fields{before.status, before.pid, before.metadata},
+		fields{after.status, after.pid, after.metadata},
+	)
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.String()
+	}
+	return lines
+}
+
+// annotateDiffNode adds a diff class to node and appends notes to its label.
+func annotateDiffNode(node *Node, class string, notes ...string) {
+	node.Classes = append(node.Classes, class)
+	node.Label += "<br/><i>" + strings.Join(notes, "<br/>") + "</i>"
+}
+
+// pairChildren matches the children of two versions of a node.
+func pairChildren(before, after treeFields, fields FieldMapping) []childPair {
+	beforeFields, afterFields := readChildren(before, fields), readChildren(after, fields)
+	beforeNames, okBefore := childNames(beforeFields)
+	afterNames, okAfter := childNames(afterFields)
+
+	if !okBefore || !okAfter {
+		pairs := make([]childPair, max(len(before.children), len(after.children)))
+		for i := range pairs {
+			if i < len(before.children) {
+				pairs[i].before, pairs[i].b = before.children[i], beforeFields[i]
+			}
+			if i < len(after.children) {
+				pairs[i].after, pairs[i].a = after.children[i], afterFields[i]
+			}
+		}
+		return pairs
+	}
+
+	beforeIndex := make(map[string]int, len(beforeNames))
+	for i, name := range beforeNames {
+		beforeIndex[name] = i
+	}
+	matched := make([]bool, len(before.children))
+
+	var pairs []childPair
+	next := 0 // First before child not yet emitted
+	flush := func(upTo int) {
+		for ; next < upTo; next++ {
+			if !matched[next] {
+				pairs = append(pairs, childPair{before: before.children[next], b: beforeFields[next]})
+			}
+		}
+	}
+	for i, name := range afterNames {
+		j, ok := beforeIndex[name]
+		if !ok {
+			pairs = append(pairs, childPair{after: after.children[i], a: afterFields[i]})
+			continue
+		}
+		matched[j] = true
+		flush(j)
+		pairs = append(pairs, childPair{before: before.children[j], after: after.children[i], b: beforeFields[j], a: afterFields[i]})
+	}
+	flush(len(before.children))
+	return pairs
+}
+
+// readChildren reads the children of t, naming unnamed children after their map keys.
+func readChildren(t treeFields, fields FieldMapping) []treeFields {
+	children := make([]treeFields, len(t.children))
+	for i, child := range t.children {
+		children[i] = readTreeNode(child, fields)
+		children[i].name = cmp.Or(children[i].name, t.childKey(i))
+	}
+	return children
+}
+
+// childNames returns the names of children. It reports false unless every name is
+// non-empty and unique.
+func childNames(children []treeFields) ([]string, bool) {
+	names := make([]string, len(children))
+	seen := make(map[string]bool, len(children))
+	for i, child := range children {
+		if child.name == "" || seen[child.name] {
+			return nil, false
+		}
+		seen[child.name] = true
+		names[i] = child.name
+	}
+	return names, true
+}
@@ -0,0 +1,112 @@
+package introspection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildTreeDiffGraph(t *testing.T) {
+	before := graphTestNode{
+		Name:   "root",
+		Status: "Running",
+		Children: []graphTestNode{
+			{Name: "a", Status: "Running", PID: 10},
+			{Name: "b", Status: "Running", PID: 11, Metadata: map[string]string{"restarts": "0"}},
+			{Name: "c", Status: "Running", PID: 12},
+		},
+	}
+	after := graphTestNode{
+		Name:   "root",
+		Status: "Running",
+		Children: []graphTestNode{
+			{Name: "a", Status: "Running", PID: 10},
+			{Name: "c", Status: "Failed", PID: 12},
+			{Name: "d", Status: "Starting", PID: 20},
+		},
+	}
+	after.Children[0].Children = []graphTestNode{{Name: "a1"}}
+
+	config := DefaultDiagramConfig()
+	config.SecondaryID = "r"
+	g := BuildTreeDiffGraph(before, after, config)
+
+	type row struct {
+		id, name, diff string
+	}
+	var got []row
+	for _, n := range g.Nodes {
+		diff := ""
+		if len(n.Classes) > 2 {
+			diff = n.Classes[2]
+		}
+		got = append(got, row{n.ID, n.Name, diff})
+	}
+	want := []row{
+		{"r", "root", ""},
+		{"r_0", "a", ""},
+		{"r_0_0", "a1", DiffAddedClass},
+		{"r_1", "b", DiffRemovedClass},
+		{"r_2", "c", DiffChangedClass},
+		{"r_3", "d", DiffAddedClass},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes = %v, want %v", got, want)
+	}
+
+	if c := g.Node("r_2"); !strings.Contains(c.Label, "<i>Status: Running → Failed</i>") || c.Status != "Failed" {
+		t.Errorf("changed node = %+v", c)
+	}
+	if b := g.Node("r_1"); !strings.Contains(b.Label, "<i>removed</i>") || b.Status != "Running" {
+		t.Errorf("removed node = %+v", b)
+	}
+	if len(g.Edges) != 5 {
+		t.Errorf("got %d edges, want 5", len(g.Edges))
+	}
+	if !strings.HasSuffix(g.Styles, DiffStyles()) {
+		t.Error("Styles should end with DiffStyles()")
+	}
+}
+
+func TestTreeDiffDiagram(t *testing.T) {
+	before := graphTestNode{Name: "svc", Status: "Running", PID: 1, Metadata: map[string]string{"restarts": "0"}}
+	after := graphTestNode{Name: "svc", Status: "Running", PID: 2, Metadata: map[string]string{"restarts": "1"}}
+
+	diagram := TreeDiffDiagram(before, after, nil)
+
+	for _, want := range []string{
+		"<i>PID: 1 → 2<br/>Metadata[restarts]: 0 → 1</i>",
+		"class secondary changed",
+		"classDef changed",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("diagram missing %q:\n%s", want, diagram)
+		}
+	}
+
+	// Unnamed children are paired by position.
+	before.Children = []graphTestNode{{Status: "Running"}, {Status: "Running"}}
+	after.Children = []graphTestNode{{Status: "Running"}}
+	g := BuildTreeDiffGraph(before, after, nil)
+	if n := g.Node("secondary_1"); n == nil || n.Classes[len(n.Classes)-1] != DiffRemovedClass {
+		t.Errorf("positional removal = %+v", n)
+	}
+}
+
+func TestBuildTreeDiffGraph_ReadsNodesOnce(t *testing.T) {
+	leaf := &lazyNode{name: "app", status: "Running"}
+	before := &lazyNode{name: "pod", status: "Running", children: []*lazyNode{leaf}}
+	after := &lazyNode{name: "pod", status: "Failed", children: []*lazyNode{leaf}}
+
+	BuildTreeDiffGraph(before, after, nil)
+
+	// The shared leaf is read once as the before child and once as the after child.
+	for _, n := range []*lazyNode{before, after} {
+		if n.calls != 1 {
+			t.Errorf("%s: NodeChildren called %d times, want 1", n.status, n.calls)
+		}
+	}
+	if leaf.calls != 2 {
+		t.Errorf("leaf: NodeChildren called %d times, want 2", leaf.calls)
+	}
+}
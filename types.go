@@ -29,3 +29,12 @@ type ComponentEvent interface {
 	Timestamp() time.Time
 	EventType() string
 }
+
+// InteractionEvent is an optional extension of ComponentEvent for events that
+// describe an interaction between two components, such as a request or a
+// shutdown signal. SequenceDiagram draws them as messages from source to target.
+type InteractionEvent interface {
+	ComponentEvent
+	SourceID() string
+	TargetID() string
+}